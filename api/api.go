@@ -4,8 +4,16 @@ import (
 	"ExeProcessManager/config"
 	"ExeProcessManager/process"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // ProcessAPI holds dependencies for the API handlers.
@@ -13,30 +21,149 @@ type ProcessAPI struct {
 	Manager *process.ProcessManager
 	Logger  *slog.Logger
 	Config  *config.Config
+	Keys    *config.KeyStore
 }
 
 // NewProcessAPI creates a new API handler instance.
-func NewProcessAPI(pm *process.ProcessManager, logger *slog.Logger, cfg *config.Config) *ProcessAPI {
+func NewProcessAPI(pm *process.ProcessManager, logger *slog.Logger, cfg *config.Config, keys *config.KeyStore) *ProcessAPI {
 	return &ProcessAPI{
 		Manager: pm,
 		Logger:  logger,
 		Config:  cfg,
+		Keys:    keys,
 	}
 }
 
+// apiVersion prefixes the resource-oriented routes.
+const apiVersion = "/v1"
+
+// route describes an API route, both for the mux and for the OpenAPI
+// document, so the two cannot disagree.
+type route struct {
+	pattern      string // method and path, as for http.ServeMux
+	handler      http.HandlerFunc
+	scope        string // API key scope the route requires
+	id           string // OpenAPI operation ID
+	summary      string
+	query        []queryParam
+	request      any  // value of the type the handler decodes the body into, nil for none
+	bodyOptional bool // an empty body is accepted too
+	response     any  // value of the type of a successful response body
+	status       int  // of a successful response, http.StatusOK if zero
+	unversioned  bool // also served, deprecated, without the /v1 prefix
+	deprecated   bool
+}
+
+// queryParam is a query string parameter of a route.
+type queryParam struct {
+	name, kind, description string // kind is an OpenAPI type such as "integer"
+}
+
+// routes returns every route the API serves.
+func (api *ProcessAPI) routes() []route {
+	routes := []route{
+		{pattern: "GET /v1/processes", handler: api.getProcesses, scope: config.ScopeRead, id: "listProcesses", summary: "List all processes with their configuration and status",
+			response: []processResponse{}},
+		{pattern: "GET /v1/processes/{name}", handler: api.getProcess, scope: config.ScopeRead, id: "getProcess", summary: "Get a process with its configuration and status",
+			response: processResponse{}},
+		{pattern: "PUT /v1/processes/{name}", handler: api.putProcess, scope: config.ScopeAdmin, id: "putProcess", summary: "Add a process or replace its configuration",
+			request: processSpec{}, response: processResponse{}},
+		{pattern: "PATCH /v1/processes/{name}", handler: api.patchProcess, scope: config.ScopeAdmin, id: "patchProcess", summary: "Change the given fields of the configuration of a process",
+			request: processSpec{}, response: processResponse{}},
+		{pattern: "DELETE /v1/processes/{name}", handler: api.deleteProcess, scope: config.ScopeAdmin, id: "deleteProcess", summary: "Stop a process if needed and remove it",
+			response: messageResponse{}},
+		{pattern: "POST /v1/processes/{name}/actions/start", handler: api.startAction, scope: config.ScopeControl, id: "startProcess", summary: "Start a process",
+			request: startRequest{}, bodyOptional: true, response: processResponse{}},
+		{pattern: "POST /v1/processes/{name}/actions/stop", handler: api.stopAction, scope: config.ScopeControl, id: "stopProcess", summary: "Stop a process",
+			request: stopRequest{}, bodyOptional: true, response: processResponse{}},
+		{pattern: "POST /v1/processes/{name}/actions/restart", handler: api.restartAction, scope: config.ScopeControl, id: "restartProcess", summary: "Stop a process if it is running and start it again",
+			request: startRequest{}, bodyOptional: true, response: processResponse{}},
+		{pattern: "POST /v1/processes/{name}/actions/signal", handler: api.signalAction, scope: config.ScopeControl, id: "signalProcess", summary: "Send a signal to a running process",
+			request: signalRequest{}, response: processResponse{}},
+
+		{pattern: "GET /v1/processes/{name}/tree", handler: api.processTree, scope: config.ScopeRead, id: "getProcessTree", summary: "Get the running process and its descendants",
+			response: process.TreeNode{}, unversioned: true},
+		{pattern: "GET /v1/processes/{name}/logs", handler: api.processLogs, scope: config.ScopeRead, id: "getProcessLogs", summary: "Get captured output; with follow=true it is streamed as Server-Sent Events",
+			query: []queryParam{
+				{"tail", "integer", "number of lines, 100 by default or all lines of a time range"},
+				{"follow", "boolean", "keep streaming new lines as text/event-stream"},
+				{"stream", "string", "stdout or stderr, both if empty"},
+				{"since", "string", "RFC 3339 time of the first line"},
+				{"until", "string", "RFC 3339 time after the last line, not with follow"},
+			},
+			response: []process.LogLine{}, unversioned: true},
+		{pattern: "GET /v1/processes/{name}/runs", handler: api.processRuns, scope: config.ScopeRead, id: "listProcessRuns", summary: "List the most recent runs of a process, newest first",
+			query:    []queryParam{{"limit", "integer", "number of runs, 20 by default"}},
+			response: []process.Run{}, unversioned: true},
+		{pattern: "PUT /v1/processes/{name}/job", handler: api.setJob, scope: config.ScopeAdmin, id: "setJob", summary: "Set a timing rule as the job of a process",
+			request: setJobRequest{}, response: messageResponse{}, unversioned: true},
+		{pattern: "DELETE /v1/processes/{name}/job", handler: api.unsetJob, scope: config.ScopeAdmin, id: "unsetJob", summary: "Cancel the job of a process and remove its timing rule",
+			response: messageResponse{}, unversioned: true},
+		{pattern: "POST /v1/processes/{name}/job/start", handler: api.startJob, scope: config.ScopeControl, id: "startJob", summary: "Start the job of a process",
+			response: messageResponse{}, unversioned: true},
+		{pattern: "POST /v1/processes/{name}/job/cancel", handler: api.cancelJob, scope: config.ScopeControl, id: "cancelJob", summary: "Cancel the pending occurrences of the job of a process",
+			response: messageResponse{}, unversioned: true},
+		{pattern: "GET /v1/rules", handler: api.listRules, scope: config.ScopeRead, id: "listRules", summary: "List all timing rules with their next fire time",
+			response: []ruleResponse{}, unversioned: true},
+		{pattern: "POST /v1/rules", handler: api.createRule, scope: config.ScopeAdmin, id: "createRule", summary: "Create a timing rule",
+			request: createRuleRequest{}, response: ruleResponse{}, status: http.StatusCreated, unversioned: true},
+		{pattern: "GET /v1/rules/{name}", handler: api.getRule, scope: config.ScopeRead, id: "getRule", summary: "Get a timing rule",
+			response: ruleResponse{}, unversioned: true},
+		{pattern: "DELETE /v1/rules/{name}", handler: api.deleteRule, scope: config.ScopeAdmin, id: "deleteRule", summary: "Delete a timing rule that is not the job of a process",
+			response: messageResponse{}, unversioned: true},
+		{pattern: "GET /v1/keys", handler: api.listKeys, scope: config.ScopeAdmin, id: "listKeys", summary: "List the API keys without their secrets",
+			response: []config.APIKey{}},
+		{pattern: "POST /v1/keys", handler: api.mintKey, scope: config.ScopeAdmin, id: "mintKey", summary: "Mint an API key; the response is the only time the key is shown",
+			request: mintKeyRequest{}, response: config.APIKey{}, status: http.StatusCreated},
+		{pattern: "DELETE /v1/keys/{name}", handler: api.revokeKey, scope: config.ScopeAdmin, id: "revokeKey", summary: "Revoke a minted API key",
+			response: messageResponse{}},
+		{pattern: "GET /v1/schedule", handler: api.schedule, scope: config.ScopeRead, id: "listSchedule", summary: "List the next fire time of every active job, soonest first",
+			response: []process.ScheduledJob{}, unversioned: true},
+
+		// Verb-style routes that take the process name in the body.
+		{pattern: "GET /processes", handler: deprecated("/v1/processes", api.listProcesses), scope: config.ScopeRead, id: "listProcessesLegacy", summary: "List all processes",
+			response: []map[string]any{}, deprecated: true},
+		{pattern: "POST /processes/add", handler: deprecated("/v1/processes", api.addProcess), scope: config.ScopeAdmin, id: "addProcessLegacy", summary: "Add a process",
+			request: addProcessRequest{}, response: process.Process{}, status: http.StatusCreated, deprecated: true},
+		{pattern: "POST /processes/start", handler: deprecated("/v1/processes", api.startProcess), scope: config.ScopeControl, id: "startProcessLegacy", summary: "Start a process",
+			request: nameStartRequest{}, response: messageResponse{}, deprecated: true},
+		{pattern: "POST /processes/stop", handler: deprecated("/v1/processes", api.stopProcess), scope: config.ScopeControl, id: "stopProcessLegacy", summary: "Stop a process",
+			request: nameStopRequest{}, response: messageResponse{}, deprecated: true},
+
+		{pattern: "GET /openapi.json", handler: api.openAPI, scope: config.ScopeRead, id: "getOpenAPI", summary: "Get this OpenAPI document",
+			response: map[string]any{}},
+	}
+
+	for _, r := range routes {
+		if !r.unversioned {
+			continue
+		}
+		method, path, _ := strings.Cut(r.pattern, " ")
+		r.pattern = method + " " + strings.TrimPrefix(path, apiVersion)
+		r.handler = deprecated("", r.handler)
+		r.id += "Unversioned"
+		r.unversioned, r.deprecated = false, true
+		routes = append(routes, r)
+	}
+	return routes
+}
+
+// newMux registers every route on a new mux, each behind the check of the
+// key scope it requires.
+func (api *ProcessAPI) newMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	for _, r := range api.routes() {
+		mux.HandleFunc(r.pattern, api.authorize(r))
+	}
+	return mux
+}
+
 // Routes sets up all the API routes and returns an http.Handler.
 // It now chains the authentication middleware with the logger middleware.
 func (api *ProcessAPI) Routes() http.Handler {
-	mux := http.NewServeMux()
-
-	mux.HandleFunc("GET /processes", api.listProcesses)
-	mux.HandleFunc("POST /processes/add", api.addProcess)
-	mux.HandleFunc("POST /processes/start", api.startProcess)
-	mux.HandleFunc("POST /processes/stop", api.stopProcess)
-
 	// Chain the middlewares: the request first hits the logger, then authentication.
 	// You can reverse the order if you prefer.
-	var handler http.Handler = mux
+	var handler http.Handler = api.newMux()
 	handler = api.authMiddleware(handler)
 	handler = api.logRequests(handler)
 
@@ -45,46 +172,194 @@ func (api *ProcessAPI) Routes() http.Handler {
 
 // --- Handlers (No changes below this line) ---
 
+// processSpec is the configuration of a process as accepted by the API.
+type processSpec struct {
+	Path        string                 `json:"path"`
+	Schedul     int                    `json:"schedul"`
+	Labels      map[string]string      `json:"labels"`
+	Restart     *process.RestartPolicy `json:"restart"`
+	StopSignal  string                 `json:"stop_signal"`
+	StopTimeout process.Duration       `json:"stop_timeout"`
+	Args        []string               `json:"args"`
+	Env         map[string]string      `json:"env"`
+	EnvFiles    []string               `json:"env_files"`
+	Dir         string                 `json:"dir"`
+	InheritEnv  *bool                  `json:"inherit_env"` // defaults to true
+	User        string                 `json:"user"`
+	Group       string                 `json:"group"`
+	Groups      []string               `json:"supplementary_groups"`
+	Limits      *process.Limits        `json:"limits"`
+}
+
+// specOf returns the configuration of a process as a spec.
+func specOf(p process.Process) processSpec {
+	inheritEnv := p.InheritEnv
+	return processSpec{
+		Path:        p.Path,
+		Schedul:     p.Schedul,
+		Labels:      p.Labels,
+		Restart:     p.Restart,
+		StopSignal:  p.StopSignal,
+		StopTimeout: p.StopTimeout,
+		Args:        p.Args,
+		Env:         p.Env,
+		EnvFiles:    p.EnvFiles,
+		Dir:         p.Dir,
+		InheritEnv:  &inheritEnv,
+		User:        p.User,
+		Group:       p.Group,
+		Groups:      p.SupplementaryGroups,
+		Limits:      p.Limits,
+	}
+}
+
+// validate checks the fields the process options do not.
+func (spec processSpec) validate() error {
+	if spec.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if spec.Schedul != 0 && spec.Schedul != 1 {
+		return fmt.Errorf("schedul must be 0 or 1")
+	}
+	return nil
+}
+
+// options turns the spec into process options.
+func (spec processSpec) options() []process.ProcessOption {
+	var opts []process.ProcessOption
+	if len(spec.Labels) > 0 {
+		opts = append(opts, process.WithLabels(spec.Labels))
+	}
+	if spec.Restart != nil {
+		opts = append(opts, process.WithRestartPolicy(*spec.Restart))
+	}
+	if spec.StopSignal != "" {
+		opts = append(opts, process.WithStopSignal(spec.StopSignal))
+	}
+	if spec.StopTimeout != 0 {
+		opts = append(opts, process.WithStopTimeout(time.Duration(spec.StopTimeout)))
+	}
+	if len(spec.Args) > 0 {
+		opts = append(opts, process.WithArgs(spec.Args...))
+	}
+	if len(spec.Env) > 0 {
+		opts = append(opts, process.WithEnv(spec.Env))
+	}
+	if len(spec.EnvFiles) > 0 {
+		opts = append(opts, process.WithEnvFiles(spec.EnvFiles...))
+	}
+	if spec.Dir != "" {
+		opts = append(opts, process.WithDir(spec.Dir))
+	}
+	if spec.InheritEnv != nil {
+		opts = append(opts, process.WithInheritEnv(*spec.InheritEnv))
+	}
+	if spec.User != "" {
+		opts = append(opts, process.WithUser(spec.User))
+	}
+	if spec.Group != "" {
+		opts = append(opts, process.WithGroup(spec.Group))
+	}
+	if len(spec.Groups) > 0 {
+		opts = append(opts, process.WithSupplementaryGroups(spec.Groups...))
+	}
+	if spec.Limits != nil {
+		opts = append(opts, process.WithLimits(*spec.Limits))
+	}
+	return opts
+}
+
+// processResponse is a process as returned by the v1 routes.
+type processResponse struct {
+	process.Process
+	Status string `json:"status"` // as shown by the CLI, e.g. "stopped (restarting)"
+}
+
+func newProcessResponse(p *process.Process) processResponse {
+	return processResponse{Process: p.Snapshot(), Status: p.GetStatus()}
+}
+
+// startRequest holds the per-start options of a start or restart.
+type startRequest struct {
+	Args     []string         `json:"args"`
+	ArgsMode string           `json:"args_mode"` // replace (default) or append
+	Timeout  process.Duration `json:"timeout"`   // optional run timeout
+}
+
+// options validates the request and turns it into start options.
+func (req startRequest) options() (process.StartOptions, error) {
+	if err := process.ValidateArgsMode(req.ArgsMode); err != nil {
+		return process.StartOptions{}, err
+	}
+	if req.Timeout < 0 {
+		return process.StartOptions{}, fmt.Errorf("timeout must not be negative")
+	}
+	return process.StartOptions{Args: req.Args, ArgsMode: req.ArgsMode, Timeout: time.Duration(req.Timeout), Trigger: process.TriggerAPI}, nil
+}
+
+// stopRequest holds the per-stop overrides of a stop.
+type stopRequest struct {
+	Signal  string           `json:"signal"`  // optional override of the stop signal
+	Timeout process.Duration `json:"timeout"` // optional override of the stop timeout
+}
+
+// decodeOptionalBody decodes a JSON request body into v. Unlike a plain
+// decode, an empty body is fine and leaves v as it is.
+func decodeOptionalBody(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
 func (api *ProcessAPI) listProcesses(w http.ResponseWriter, r *http.Request) {
-	procs := api.Manager.Processes
-	response := make([]map[string]interface{}, len(procs))
-	for i, p := range procs {
-		response[i] = map[string]interface{}{
-			"name":   p.Name,
-			"pid":    p.Pid,
-			"status": p.GetStatus(),
-			"path":   p.Path,
+	response := []map[string]interface{}{}
+	for _, p := range api.Manager.List() {
+		if !api.permitted(r, p.Name) {
+			continue
 		}
+		snap := p.Snapshot()
+		response = append(response, map[string]interface{}{
+			"name":     snap.Name,
+			"pid":      snap.Pid,
+			"status":   p.GetStatus(),
+			"path":     snap.Path,
+			"restarts": snap.Restarts,
+		})
 	}
 	respondWithJSON(w, http.StatusOK, response)
 }
 
-func (api *ProcessAPI) addProcess(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Name    string `json:"name"`
-		Path    string `json:"path"`
-		Schedul int    `json:"schedul"`
-	}
+// addProcessRequest is the body of the legacy add route.
+type addProcessRequest struct {
+	Name string `json:"name"`
+	processSpec
+}
 
+func (api *ProcessAPI) addProcess(w http.ResponseWriter, r *http.Request) {
+	var req addProcessRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	proc, err := api.Manager.AddProcess(req.Name, req.Path, req.Schedul)
+	proc, err := api.Manager.AddProcess(req.Name, req.Path, req.Schedul, req.options()...)
 	if err != nil {
-		respondWithError(w, http.StatusConflict, err.Error())
+		respondWithProcessError(w, err)
 		return
 	}
 
 	respondWithJSON(w, http.StatusCreated, proc)
 }
 
+// nameStartRequest is the body of the legacy start route.
+type nameStartRequest struct {
+	Name string `json:"name"`
+	startRequest
+}
+
 func (api *ProcessAPI) startProcess(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Name string   `json:"name"`
-		Args []string `json:"args"`
-	}
+	var req nameStartRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
@@ -92,21 +367,30 @@ func (api *ProcessAPI) startProcess(w http.ResponseWriter, r *http.Request) {
 
 	proc, err := api.Manager.GetProcessByName(req.Name)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, err.Error())
+		respondWithProcessError(w, err)
 		return
 	}
 
-	if err := proc.Start(req.Args...); err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+	opts, err := req.options()
+	if err != nil {
+		respondWithCode(w, http.StatusBadRequest, codeInvalidSpec, err.Error())
+		return
+	}
+	if err := proc.StartWith(opts); err != nil {
+		respondWithProcessError(w, err)
 		return
 	}
-	respondWithJSON(w, http.StatusOK, map[string]string{"message": "process started"})
+	respondWithJSON(w, http.StatusOK, messageResponse{Message: "process started"})
+}
+
+// nameStopRequest is the body of the legacy stop route.
+type nameStopRequest struct {
+	Name string `json:"name"`
+	stopRequest
 }
 
 func (api *ProcessAPI) stopProcess(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Name string `json:"name"`
-	}
+	var req nameStopRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
@@ -114,21 +398,273 @@ func (api *ProcessAPI) stopProcess(w http.ResponseWriter, r *http.Request) {
 
 	proc, err := api.Manager.GetProcessByName(req.Name)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, err.Error())
+		respondWithProcessError(w, err)
+		return
+	}
+
+	if err := proc.StopWith(req.Signal, time.Duration(req.Timeout)); err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, messageResponse{Message: "process stopped"})
+}
+
+func (api *ProcessAPI) processTree(w http.ResponseWriter, r *http.Request) {
+	proc, err := api.Manager.GetProcessByName(r.PathValue("name"))
+	if err != nil {
+		respondWithProcessError(w, err)
 		return
 	}
 
-	if err := proc.Stop(); err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+	tree, err := proc.Tree()
+	if err != nil {
+		respondWithProcessError(w, err)
 		return
 	}
-	respondWithJSON(w, http.StatusOK, map[string]string{"message": "process stopped"})
+	respondWithJSON(w, http.StatusOK, tree)
+}
+
+// defaultLogTail is the number of lines returned when no tail is given.
+const defaultLogTail = 100
+
+// processLogs returns the last lines of captured output. With follow=true
+// it keeps the connection open and streams new lines as Server-Sent Events.
+func (api *ProcessAPI) processLogs(w http.ResponseWriter, r *http.Request) {
+	proc, err := api.Manager.GetProcessByName(r.PathValue("name"))
+	if err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	since, err := timeParam(query, "since")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	until, err := timeParam(query, "until")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// A time range selects all of its lines unless a tail is given too.
+	tail := defaultLogTail
+	if !since.IsZero() || !until.IsZero() {
+		tail = -1
+	}
+	if value := query.Get("tail"); value != "" {
+		tail, err = strconv.Atoi(value)
+		if err != nil || tail < 0 {
+			respondWithError(w, http.StatusBadRequest, "tail must be a non-negative integer")
+			return
+		}
+	}
+	stream := query.Get("stream")
+	if err := process.ValidateStream(stream); err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	follow := query.Get("follow") == "true"
+	if follow && !until.IsZero() {
+		respondWithError(w, http.StatusBadRequest, "until cannot be combined with follow")
+		return
+	}
+
+	// Subscribe before reading the tail so no line falls between the two.
+	var lines <-chan process.LogLine
+	if follow {
+		var cancel func()
+		lines, cancel, err = proc.FollowLogs(stream)
+		if err != nil {
+			respondWithProcessError(w, err)
+			return
+		}
+		defer cancel()
+	}
+
+	history, err := proc.LogsBetween(since, until, tail, stream)
+	if err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	if !follow {
+		if history == nil {
+			history = []process.LogLine{}
+		}
+		respondWithJSON(w, http.StatusOK, history)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	for _, line := range history {
+		writeLogEvent(w, line)
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case line, open := <-lines:
+			if !open {
+				return // The process was removed
+			}
+			writeLogEvent(w, line)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// timeParam parses an optional RFC 3339 time from the query string.
+func timeParam(query url.Values, key string) (time.Time, error) {
+	value := query.Get(key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 time", key)
+	}
+	return t, nil
+}
+
+// defaultRunsLimit is the number of runs returned when no limit is given.
+const defaultRunsLimit = 20
+
+// processRuns returns the most recent runs of a process, newest first.
+func (api *ProcessAPI) processRuns(w http.ResponseWriter, r *http.Request) {
+	proc, err := api.Manager.GetProcessByName(r.PathValue("name"))
+	if err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+
+	limit := defaultRunsLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			respondWithError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+	}
+
+	runs, err := proc.Runs()
+	if err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	if len(runs) > limit {
+		runs = runs[len(runs)-limit:]
+	}
+	slices.Reverse(runs)
+	if runs == nil {
+		runs = []process.Run{}
+	}
+	respondWithJSON(w, http.StatusOK, runs)
+}
+
+// writeLogEvent writes one log line as a Server-Sent Event.
+func writeLogEvent(w io.Writer, line process.LogLine) {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "data: %s\n\n", data)
 }
 
 // --- Helper Functions (No changes here) ---
 
-func respondWithError(w http.ResponseWriter, code int, message string) {
-	respondWithJSON(w, code, map[string]string{"error": message})
+// messageResponse is the body of a successful request that returns no data.
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+// errorResponse is the body of every failed request.
+type errorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"` // machine-readable, such as "already_running"
+}
+
+// Codes of errorResponse.
+const (
+	codeInvalidRequest = "invalid_request" // the body or query cannot be read
+	codeInvalidSpec    = "invalid_spec"    // a value in the request is not valid
+	codeUnauthorized   = "unauthorized"
+	codeForbidden      = "forbidden"
+	codeKeyExpired     = "key_expired"
+	codeReadOnly       = "read_only"
+	codeNotFound       = "not_found"
+	codeAlreadyExists  = "already_exists"
+	codeAlreadyRunning = "already_running"
+	codeNotRunning     = "not_running"
+	codeScheduledOnly  = "scheduled_only"
+	codeNotScheduled   = "not_scheduled"
+	codeInUse          = "in_use"
+	codeInternal       = "internal_error"
+)
+
+// statusCodes gives the error code of a status when there is nothing more
+// specific to say.
+var statusCodes = map[int]string{
+	http.StatusBadRequest:   codeInvalidRequest,
+	http.StatusUnauthorized: codeUnauthorized,
+	http.StatusForbidden:    codeForbidden,
+}
+
+// processErrors gives the status and error code of each kind of error of
+// the process package.
+var processErrors = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{process.ErrNotFound, http.StatusNotFound, codeNotFound},
+	{process.ErrAlreadyExists, http.StatusConflict, codeAlreadyExists},
+	{process.ErrAlreadyRunning, http.StatusConflict, codeAlreadyRunning},
+	{process.ErrNotRunning, http.StatusConflict, codeNotRunning},
+	{process.ErrScheduledOnly, http.StatusConflict, codeScheduledOnly},
+	{process.ErrNotScheduled, http.StatusConflict, codeNotScheduled},
+	{process.ErrInUse, http.StatusConflict, codeInUse},
+	{process.ErrInvalidSpec, http.StatusBadRequest, codeInvalidSpec},
+}
+
+// respondWithError writes an error with the code of its status.
+func respondWithError(w http.ResponseWriter, status int, message string) {
+	code, ok := statusCodes[status]
+	if !ok {
+		code = codeInternal
+	}
+	respondWithCode(w, status, code, message)
+}
+
+// respondWithProcessError writes an error returned by the process package
+// with the status and code of its kind. Errors of no known kind are
+// failures of the server.
+func respondWithProcessError(w http.ResponseWriter, err error) {
+	for _, kind := range processErrors {
+		if errors.Is(err, kind.err) {
+			respondWithCode(w, kind.status, kind.code, err.Error())
+			return
+		}
+	}
+	respondWithCode(w, http.StatusInternalServerError, codeInternal, err.Error())
+}
+
+func respondWithCode(w http.ResponseWriter, status int, code, message string) {
+	respondWithJSON(w, status, errorResponse{Error: message, Code: code})
 }
 
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
@@ -137,11 +673,11 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 		slog.Error("failed to marshal JSON response", "error", err)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`{"error": "Internal Server Error"}`))
+		w.Write([]byte(`{"error": "Internal Server Error", "code": "internal_error"}`))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 	w.Write(response)
-}
\ No newline at end of file
+}
@@ -3,25 +3,37 @@ package api
 import (
 	"ExeProcessManager/config"
 	"ExeProcessManager/process"
-	"bytes"
+	"bufio"
+	"context"
 	"encoding/json"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
+// testAPIKey is the key configured for every API test.
+const testAPIKey = "test-api-key"
+
 // setupAPITest is a helper function to create all necessary components for an API test.
 func setupAPITest(t *testing.T) (*ProcessAPI, *process.ProcessManager) {
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil)) // Discard logs during tests
 	cfg := &config.Config{
 		DataDir:     t.TempDir(),
 		ScheduleDir: t.TempDir(),
+		ApiKeys:     []config.APIKey{{Name: "test", Key: testAPIKey, Scopes: []string{config.ScopeAdmin}}},
 	}
 	pm := process.NewProcessManager(logger, cfg)
-	api := NewProcessAPI(pm, logger, cfg)
+	keys, err := config.NewKeyStore(cfg)
+	if err != nil {
+		t.Fatalf("failed to create key store: %v", err)
+	}
+	api := NewProcessAPI(pm, logger, cfg, keys)
 	return api, pm
 }
 
@@ -34,6 +46,7 @@ func TestListProcessesHandler(t *testing.T) {
 
 	// Create a new HTTP request and a recorder to capture the response
 	req := httptest.NewRequest(http.MethodGet, "/processes", nil)
+	req.Header.Set("X-API-KEY", testAPIKey)
 	rr := httptest.NewRecorder()
 
 	// Serve the request using the main router to include middleware
@@ -58,6 +71,7 @@ func TestAddProcessHandler(t *testing.T) {
 	payload := `{"name":"api-proc","path":"/bin/echo","schedul":0}`
 	req := httptest.NewRequest(http.MethodPost, "/processes/add", strings.NewReader(payload))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-KEY", testAPIKey)
 	rr := httptest.NewRecorder()
 
 	api.Routes().ServeHTTP(rr, req)
@@ -81,4 +95,556 @@ func TestAddProcessHandler(t *testing.T) {
 	if name, ok := response["name"]; !ok || name != "api-proc" {
 		t.Errorf("response body does not have the correct name: got %v", response)
 	}
-}
\ No newline at end of file
+}
+
+// TestProcessTreeHandler tests the GET /processes/{name}/tree endpoint.
+func TestProcessTreeHandler(t *testing.T) {
+	api, pm := setupAPITest(t)
+
+	proc, _ := pm.AddProcess("tree-proc", "sleep", 0)
+	if err := proc.Start("10"); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	defer proc.Stop()
+
+	req := httptest.NewRequest(http.MethodGet, "/processes/tree-proc/tree", nil)
+	req.Header.Set("X-API-KEY", testAPIKey)
+	rr := httptest.NewRecorder()
+	api.Routes().ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", status, http.StatusOK, rr.Body.String())
+	}
+	var tree process.TreeNode
+	if err := json.NewDecoder(rr.Body).Decode(&tree); err != nil {
+		t.Fatalf("could not decode response body: %v", err)
+	}
+	if tree.Pid != proc.Pid {
+		t.Errorf("expected root PID %d, got %d", proc.Pid, tree.Pid)
+	}
+
+	// Unknown processes are reported as not found.
+	req = httptest.NewRequest(http.MethodGet, "/processes/missing/tree", nil)
+	req.Header.Set("X-API-KEY", testAPIKey)
+	rr = httptest.NewRecorder()
+	api.Routes().ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown process, got %v", rr.Code)
+	}
+}
+
+// TestProcessLogsHandler tests tailing and following GET /processes/{name}/logs.
+func TestProcessLogsHandler(t *testing.T) {
+	api, pm := setupAPITest(t)
+
+	proc, _ := pm.AddProcess("log-proc", "/bin/sh", 0)
+	if err := proc.Start("-c", "echo first; echo oops >&2; echo second; sleep 0.3; echo live"); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	time.Sleep(150 * time.Millisecond) // Let the first lines reach the log
+
+	req := httptest.NewRequest(http.MethodGet, "/processes/log-proc/logs?tail=1&stream=stdout", nil)
+	req.Header.Set("X-API-KEY", testAPIKey)
+	rr := httptest.NewRecorder()
+	api.Routes().ServeHTTP(rr, req)
+
+	var lines []process.LogLine
+	if err := json.NewDecoder(rr.Body).Decode(&lines); err != nil {
+		t.Fatalf("could not decode response body: %v", err)
+	}
+	if len(lines) != 1 || lines[0].Text != "second" || lines[0].Stream != "stdout" {
+		t.Errorf("expected only the last stdout line, got %+v", lines)
+	}
+
+	// Follow mode streams new lines as Server-Sent Events until the client leaves.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	followReq, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/processes/log-proc/logs?tail=0&follow=true", nil)
+	followReq.Header.Set("X-API-KEY", testAPIKey)
+	resp, err := http.DefaultClient.Do(followReq)
+	if err != nil {
+		t.Fatalf("follow request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream, got content type %q", ct)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "data: ") && strings.Contains(scanner.Text(), `"text":"live"`) {
+			return
+		}
+	}
+	t.Fatal("the live line was never streamed")
+}
+
+// TestStartProcessTimeout tests the timeout field of POST /processes/start.
+func TestStartProcessTimeout(t *testing.T) {
+	api, pm := setupAPITest(t)
+	proc, _ := pm.AddProcess("batch", "sleep", 0)
+
+	payload := `{"name":"batch","args":["5"],"timeout":"200ms"}`
+	req := httptest.NewRequest(http.MethodPost, "/processes/start", strings.NewReader(payload))
+	req.Header.Set("X-API-KEY", testAPIKey)
+	rr := httptest.NewRecorder()
+	api.Routes().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for proc.IsRunning() {
+		if time.Now().After(deadline) {
+			t.Fatal("run was not stopped at its timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if proc.ExitReason != process.ExitReasonTimedOut {
+		t.Errorf("expected the run to be recorded as timed out, got %q", proc.ExitReason)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/processes/start", strings.NewReader(`{"name":"batch","timeout":"-1s"}`))
+	req.Header.Set("X-API-KEY", testAPIKey)
+	rr = httptest.NewRecorder()
+	api.Routes().ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected a negative timeout to be rejected, got %v", rr.Code)
+	}
+}
+
+// TestProcessRunsHandler tests the GET /processes/{name}/runs endpoint.
+func TestProcessRunsHandler(t *testing.T) {
+	api, pm := setupAPITest(t)
+	proc, _ := pm.AddProcess("runner", "/bin/sh", 0)
+	for _, code := range []string{"0", "1"} {
+		if err := proc.Start("-c", "echo run; exit "+code); err != nil {
+			t.Fatalf("failed to start process: %v", err)
+		}
+		for proc.IsRunning() {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/processes/runner/runs?limit=1", nil)
+	req.Header.Set("X-API-KEY", testAPIKey)
+	rr := httptest.NewRecorder()
+	api.Routes().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	var runs []process.Run
+	if err := json.Unmarshal(rr.Body.Bytes(), &runs); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(runs) != 1 || runs[0].Status != process.RunFailed || runs[0].Trigger != process.TriggerManual {
+		t.Fatalf("expected only the latest, failed run, got %+v", runs)
+	}
+
+	// The logs link of a run returns the output of that run.
+	req = httptest.NewRequest(http.MethodGet, runs[0].Logs, nil)
+	req.Header.Set("X-API-KEY", testAPIKey)
+	rr = httptest.NewRecorder()
+	api.Routes().ServeHTTP(rr, req)
+	var lines []process.LogLine
+	if err := json.Unmarshal(rr.Body.Bytes(), &lines); err != nil || len(lines) != 1 {
+		t.Errorf("expected the single line of the run, got %s", rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/processes/runner/runs?limit=0", nil)
+	req.Header.Set("X-API-KEY", testAPIKey)
+	rr = httptest.NewRecorder()
+	api.Routes().ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid limit to be rejected, got %v", rr.Code)
+	}
+}
+
+// TestScheduleHandlers tests the rule, job and schedule endpoints.
+func TestScheduleHandlers(t *testing.T) {
+	api, pm := setupAPITest(t)
+	proc, _ := pm.AddProcess("report", "/bin/true", 1)
+	defer proc.CancelJob()
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-API-KEY", testAPIKey)
+		rr := httptest.NewRecorder()
+		api.Routes().ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve(http.MethodPost, "/rules", `{"name":"hourly","schedule":"0 * * * *","time_zone":"UTC","retry":{"max_retries":2}}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected the rule to be created, got %v (%s)", rr.Code, rr.Body.String())
+	}
+	if rr = serve(http.MethodPost, "/rules", `{"name":"hourly","schedule":"every 1h"}`); rr.Code != http.StatusConflict {
+		t.Errorf("expected a duplicate rule to be rejected, got %v", rr.Code)
+	}
+	if rr = serve(http.MethodPost, "/rules", `{"name":"broken","schedule":"every 1h","misfire":"sometimes"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid rule to be rejected, got %v", rr.Code)
+	}
+
+	rr = serve(http.MethodGet, "/rules/hourly", "")
+	var rule struct {
+		Name    string               `json:"name"`
+		Cron    string               `json:"cron"`
+		Retry   *process.RetryPolicy `json:"retry"`
+		NextRun *time.Time           `json:"next_run"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &rule); err != nil || rule.Cron != "0 * * * *" || rule.Retry == nil || rule.NextRun == nil {
+		t.Fatalf("expected the rule with its next fire time, got %s (err=%v)", rr.Body.String(), err)
+	}
+	if rr = serve(http.MethodGet, "/rules", ""); !strings.Contains(rr.Body.String(), `"name":"hourly"`) {
+		t.Errorf("expected the rule to be listed, got %s", rr.Body.String())
+	}
+
+	if rr = serve(http.MethodPut, "/processes/report/job", `{"rule":"missing"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("expected an unknown rule to be rejected, got %v", rr.Code)
+	}
+	if rr = serve(http.MethodPut, "/processes/report/job", `{"rule":"hourly"}`); rr.Code != http.StatusOK {
+		t.Fatalf("expected the job to be set, got %v (%s)", rr.Code, rr.Body.String())
+	}
+	if rr = serve(http.MethodPost, "/processes/report/job/start", ""); rr.Code != http.StatusOK {
+		t.Fatalf("expected the job to start, got %v (%s)", rr.Code, rr.Body.String())
+	}
+	if rr = serve(http.MethodDelete, "/rules/hourly", ""); rr.Code != http.StatusConflict {
+		t.Errorf("expected a rule in use to be kept, got %v", rr.Code)
+	}
+
+	rr = serve(http.MethodGet, "/schedule", "")
+	var jobs []process.ScheduledJob
+	if err := json.Unmarshal(rr.Body.Bytes(), &jobs); err != nil || len(jobs) != 1 || jobs[0].Process != "report" || jobs[0].Rule != "hourly" || jobs[0].NextRun.IsZero() {
+		t.Fatalf("expected the upcoming occurrence of the job, got %s (err=%v)", rr.Body.String(), err)
+	}
+
+	if rr = serve(http.MethodPost, "/processes/report/job/cancel", ""); rr.Code != http.StatusOK {
+		t.Errorf("expected the job to be cancelled, got %v", rr.Code)
+	}
+	if rr = serve(http.MethodGet, "/schedule", ""); rr.Body.String() != "[]" {
+		t.Errorf("expected no upcoming occurrences, got %s", rr.Body.String())
+	}
+	if rr = serve(http.MethodDelete, "/processes/report/job", ""); rr.Code != http.StatusOK {
+		t.Errorf("expected the job to be unset, got %v", rr.Code)
+	}
+	if rr = serve(http.MethodDelete, "/rules/hourly", ""); rr.Code != http.StatusOK {
+		t.Errorf("expected the rule to be deleted, got %v (%s)", rr.Code, rr.Body.String())
+	}
+	if rr = serve(http.MethodGet, "/rules/hourly", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("expected the deleted rule to be gone, got %v", rr.Code)
+	}
+}
+
+// TestProcessResourceHandlers tests the /v1/processes/{name} routes and
+// their actions.
+func TestProcessResourceHandlers(t *testing.T) {
+	api, pm := setupAPITest(t)
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-API-KEY", testAPIKey)
+		rr := httptest.NewRecorder()
+		api.Routes().ServeHTTP(rr, req)
+		return rr
+	}
+	decode := func(rr *httptest.ResponseRecorder) processResponse {
+		t.Helper()
+		var response processResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode response %s: %v", rr.Body.String(), err)
+		}
+		return response
+	}
+
+	rr := serve(http.MethodPut, "/v1/processes/worker", `{"path":"sleep","schedul":0,"args":["5"],"env":{"MODE":"a"}}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected the process to be created, got %v (%s)", rr.Code, rr.Body.String())
+	}
+	if rr = serve(http.MethodPut, "/v1/processes/bad", `{"schedul":0}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected a process without a path to be rejected, got %v", rr.Code)
+	}
+
+	// A patch only changes the fields it names.
+	rr = serve(http.MethodPatch, "/v1/processes/worker", `{"env":{"MODE":"b"},"stop_timeout":"2s"}`)
+	if got := decode(rr); rr.Code != http.StatusOK || got.Env["MODE"] != "b" || len(got.Args) != 1 || got.StopTimeout != process.Duration(2*time.Second) {
+		t.Fatalf("expected a merged configuration, got %v %s", rr.Code, rr.Body.String())
+	}
+	rr = serve(http.MethodPut, "/v1/processes/worker", `{"path":"sleep","schedul":0}`)
+	if got := decode(rr); rr.Code != http.StatusOK || len(got.Args) != 0 || got.Env != nil {
+		t.Fatalf("expected the configuration to be replaced, got %v %s", rr.Code, rr.Body.String())
+	}
+
+	rr = serve(http.MethodPost, "/v1/processes/worker/actions/start", `{"args":["5"]}`)
+	first := decode(rr)
+	if rr.Code != http.StatusOK || first.Status != "running" || first.Pid == 0 {
+		t.Fatalf("expected the process to start, got %v %s", rr.Code, rr.Body.String())
+	}
+	if rr = serve(http.MethodPost, "/v1/processes/worker/actions/start", ""); rr.Code != http.StatusConflict {
+		t.Errorf("expected a second start to conflict, got %v", rr.Code)
+	}
+	if rr = serve(http.MethodPost, "/v1/processes/worker/actions/signal", `{"signal":"SIGCONT"}`); rr.Code != http.StatusOK {
+		t.Errorf("expected the signal to be sent, got %v (%s)", rr.Code, rr.Body.String())
+	}
+	if rr = serve(http.MethodPost, "/v1/processes/worker/actions/signal", `{"signal":"SIGBOGUS"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected an unknown signal to be rejected, got %v", rr.Code)
+	}
+	rr = serve(http.MethodPost, "/v1/processes/worker/actions/restart", `{"args":["5"]}`)
+	if got := decode(rr); rr.Code != http.StatusOK || got.Pid == 0 || got.Pid == first.Pid {
+		t.Fatalf("expected a new run after the restart, got %v %s", rr.Code, rr.Body.String())
+	}
+	if rr = serve(http.MethodPost, "/v1/processes/worker/actions/stop", `{"timeout":"1s"}`); rr.Code != http.StatusOK || decode(rr).Status != "stopped" {
+		t.Errorf("expected the process to stop, got %v %s", rr.Code, rr.Body.String())
+	}
+
+	if rr = serve(http.MethodGet, "/v1/processes/worker", ""); rr.Code != http.StatusOK || decode(rr).Name != "worker" {
+		t.Errorf("expected the process, got %v %s", rr.Code, rr.Body.String())
+	}
+	if rr = serve(http.MethodDelete, "/v1/processes/worker", ""); rr.Code != http.StatusOK {
+		t.Errorf("expected the process to be removed, got %v", rr.Code)
+	}
+	if _, err := pm.GetProcessByName("worker"); err == nil {
+		t.Error("expected the process to be gone from the manager")
+	}
+	if rr = serve(http.MethodGet, "/v1/processes/worker", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("expected a removed process to be gone, got %v", rr.Code)
+	}
+
+	// The old routes still work but announce their successors.
+	rr = serve(http.MethodGet, "/processes", "")
+	if rr.Code != http.StatusOK || rr.Header().Get("Deprecation") != "true" {
+		t.Errorf("expected the legacy list route to be served as deprecated, got %v %v", rr.Code, rr.Header())
+	}
+	rr = serve(http.MethodGet, "/rules", "")
+	if link := rr.Header().Get("Link"); link != `</v1/rules>; rel="successor-version"` {
+		t.Errorf("expected a link to the versioned route, got %q", link)
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	api, pm := setupAPITest(t)
+	if _, err := pm.AddProcess("scheduled", "/bin/true", 1); err != nil {
+		t.Fatalf("failed to add process: %v", err)
+	}
+
+	tests := []struct {
+		method, path, body string
+		status             int
+		code               string
+	}{
+		{http.MethodPost, "/processes/add", `{"name":"worker","path":"sleep","schedul":0}`, http.StatusCreated, ""},
+		{http.MethodPost, "/processes/add", `{"name":"worker","path":"sleep","schedul":0}`, http.StatusConflict, "already_exists"},
+		{http.MethodPost, "/processes/add", `{"name":"bad","path":"sleep","schedul":0,"stop_signal":"SIGBOGUS"}`, http.StatusBadRequest, "invalid_spec"},
+		{http.MethodPost, "/processes/add", `{"name":`, http.StatusBadRequest, "invalid_request"},
+		{http.MethodPost, "/processes/start", `{"name":"scheduled"}`, http.StatusConflict, "scheduled_only"},
+		{http.MethodPost, "/processes/stop", `{"name":"worker"}`, http.StatusConflict, "not_running"},
+		{http.MethodPost, "/processes/start", `{"name":"missing"}`, http.StatusNotFound, "not_found"},
+		{http.MethodPost, "/v1/processes/worker/job/start", "", http.StatusConflict, "not_scheduled"},
+		{http.MethodGet, "/v1/rules/missing", "", http.StatusNotFound, "not_found"},
+		{http.MethodPut, "/v1/processes/..%2F..%2Fescaped", `{"path":"sleep","schedul":0}`, http.StatusBadRequest, "invalid_spec"},
+		{http.MethodPost, "/v1/rules", `{"name":"../../escaped","schedule":"every 1h"}`, http.StatusBadRequest, "invalid_spec"},
+		{http.MethodDelete, "/v1/rules/..%2F..%2Fescaped", "", http.StatusBadRequest, "invalid_spec"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("X-API-KEY", testAPIKey)
+		rr := httptest.NewRecorder()
+		api.Routes().ServeHTTP(rr, req)
+
+		if rr.Code != tt.status {
+			t.Errorf("%s %s %s: expected status %v, got %v (%s)", tt.method, tt.path, tt.body, tt.status, rr.Code, rr.Body.String())
+			continue
+		}
+		if tt.code == "" {
+			continue
+		}
+		var response errorResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode error %s: %v", rr.Body.String(), err)
+		}
+		if response.Code != tt.code || response.Error == "" {
+			t.Errorf("%s %s %s: expected code %q with a message, got %+v", tt.method, tt.path, tt.body, tt.code, response)
+		}
+	}
+}
+
+func TestScopedAPIKeys(t *testing.T) {
+	api, pm := setupAPITest(t)
+	api.Config.ApiKeys = append(api.Config.ApiKeys,
+		config.APIKey{Name: "monitoring", Key: "read-key", Scopes: []string{config.ScopeRead}},
+		config.APIKey{Name: "deploy", Key: "deploy-key", Scopes: []string{config.ScopeControl}, Processes: []string{"web", "team=deploy"}},
+	)
+	keys, err := config.NewKeyStore(api.Config)
+	if err != nil {
+		t.Fatalf("failed to create key store: %v", err)
+	}
+	api.Keys = keys
+	for name, labels := range map[string]map[string]string{"web": nil, "worker": {"team": "deploy"}, "other": {"team": "data"}} {
+		if _, err := pm.AddProcess(name, "/bin/sleep", 0, process.WithLabels(labels)); err != nil {
+			t.Fatalf("failed to add process: %v", err)
+		}
+	}
+	serve := func(key, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-API-KEY", key)
+		rr := httptest.NewRecorder()
+		api.Routes().ServeHTTP(rr, req)
+		return rr
+	}
+	names := func(rr *httptest.ResponseRecorder) []string {
+		t.Helper()
+		var response []processResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode response %s: %v", rr.Body.String(), err)
+		}
+		var names []string
+		for _, p := range response {
+			names = append(names, p.Name)
+		}
+		slices.Sort(names)
+		return names
+	}
+
+	if got := names(serve("read-key", http.MethodGet, "/v1/processes", "")); len(got) != 3 {
+		t.Errorf("expected a read key to see every process, got %v", got)
+	}
+	if got := names(serve("deploy-key", http.MethodGet, "/v1/processes", "")); !slices.Equal(got, []string{"web", "worker"}) {
+		t.Errorf("expected the deploy key to see its own processes, got %v", got)
+	}
+
+	tests := []struct {
+		key, method, path, body string
+		status                  int
+	}{
+		{"read-key", http.MethodGet, "/v1/processes/other/runs", "", http.StatusOK},
+		{"read-key", http.MethodPost, "/v1/processes/web/actions/stop", "", http.StatusForbidden},
+		{"read-key", http.MethodPost, "/v1/rules", `{"name":"hourly","schedule":"every 1h"}`, http.StatusForbidden},
+		{"deploy-key", http.MethodPost, "/v1/processes/web/actions/stop", "", http.StatusConflict}, // allowed, but not running
+		{"deploy-key", http.MethodPost, "/v1/processes/worker/actions/stop", "", http.StatusConflict},
+		{"deploy-key", http.MethodPost, "/v1/processes/other/actions/stop", "", http.StatusForbidden},
+		{"deploy-key", http.MethodGet, "/v1/processes/other", "", http.StatusForbidden},
+		{"deploy-key", http.MethodDelete, "/v1/processes/web", "", http.StatusForbidden},
+		{"deploy-key", http.MethodPost, "/processes/stop", `{"name":"web"}`, http.StatusForbidden},
+		{"deploy-key", http.MethodGet, "/v1/rules", "", http.StatusOK},
+		{testAPIKey, http.MethodPost, "/v1/rules", `{"name":"hourly","schedule":"every 1h"}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		if rr := serve(tt.key, tt.method, tt.path, tt.body); rr.Code != tt.status {
+			t.Errorf("%s %s %s: expected status %v, got %v (%s)", tt.key, tt.method, tt.path, tt.status, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestKeyHandlers(t *testing.T) {
+	api, _ := setupAPITest(t)
+	serve := func(key, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-API-KEY", key)
+		rr := httptest.NewRecorder()
+		api.Routes().ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve(testAPIKey, http.MethodPost, "/v1/keys", `{"name":"monitoring","scopes":["read"]}`)
+	var minted config.APIKey
+	if err := json.Unmarshal(rr.Body.Bytes(), &minted); err != nil || rr.Code != http.StatusCreated || minted.Key == "" {
+		t.Fatalf("expected a minted key, got %v %s", rr.Code, rr.Body.String())
+	}
+	if rr = serve(minted.Key, http.MethodGet, "/v1/processes", ""); rr.Code != http.StatusOK {
+		t.Errorf("expected the minted key to work right away, got %v", rr.Code)
+	}
+	if rr = serve(minted.Key, http.MethodGet, "/v1/keys", ""); rr.Code != http.StatusForbidden {
+		t.Errorf("expected a read key not to list keys, got %v", rr.Code)
+	}
+	if rr = serve(testAPIKey, http.MethodPost, "/v1/keys", `{"name":"monitoring","scopes":["read"]}`); rr.Code != http.StatusConflict {
+		t.Errorf("expected a duplicate name to conflict, got %v", rr.Code)
+	}
+
+	rr = serve(testAPIKey, http.MethodGet, "/v1/keys", "")
+	if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), minted.Key) || strings.Contains(rr.Body.String(), "hash") {
+		t.Errorf("expected keys to be listed without secrets, got %v %s", rr.Code, rr.Body.String())
+	}
+
+	if rr = serve(testAPIKey, http.MethodDelete, "/v1/keys/monitoring", ""); rr.Code != http.StatusOK {
+		t.Errorf("expected the key to be revoked, got %v", rr.Code)
+	}
+	if rr = serve(minted.Key, http.MethodGet, "/v1/processes", ""); rr.Code != http.StatusForbidden {
+		t.Errorf("expected a revoked key to be rejected, got %v", rr.Code)
+	}
+	if rr = serve(testAPIKey, http.MethodDelete, "/v1/keys/test", ""); rr.Code != http.StatusConflict {
+		t.Errorf("expected a configured key not to be revocable, got %v", rr.Code)
+	}
+
+	expired := time.Now().Add(-time.Minute).Format(time.RFC3339)
+	rr = serve(testAPIKey, http.MethodPost, "/v1/keys", `{"name":"old","scopes":["admin"],"expires_at":"`+expired+`"}`)
+	if err := json.Unmarshal(rr.Body.Bytes(), &minted); err != nil || rr.Code != http.StatusCreated {
+		t.Fatalf("expected a minted key, got %v %s", rr.Code, rr.Body.String())
+	}
+	rr = serve(minted.Key, http.MethodGet, "/v1/processes", "")
+	var response errorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil || rr.Code != http.StatusForbidden || response.Code != "key_expired" {
+		t.Errorf("expected an expired key to be rejected, got %v %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	api, _ := setupAPITest(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	req.Header.Set("X-API-KEY", testAPIKey)
+	rr := httptest.NewRecorder()
+	api.Routes().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected the document, got %v", rr.Code)
+	}
+
+	var doc struct {
+		Paths      map[string]map[string]struct{ OperationID string }
+		Components struct {
+			Schemas map[string]struct{ Properties map[string]any }
+		}
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to decode the document: %v", err)
+	}
+
+	// Every documented operation is served by the route it names.
+	mux := api.newMux()
+	ids := map[string]bool{}
+	operations := 0
+	for path, methods := range doc.Paths {
+		for method, operation := range methods {
+			operations++
+			if ids[operation.OperationID] {
+				t.Errorf("operation id %q is used twice", operation.OperationID)
+			}
+			ids[operation.OperationID] = true
+
+			method = strings.ToUpper(method)
+			target := strings.NewReplacer("{name}", "x").Replace(path)
+			if _, pattern := mux.Handler(httptest.NewRequest(method, target, nil)); pattern != method+" "+path {
+				t.Errorf("expected %s %s to be served, got pattern %q", method, path, pattern)
+			}
+		}
+	}
+	if operations != len(api.routes()) {
+		t.Errorf("expected %d operations, got %d", len(api.routes()), operations)
+	}
+
+	// The request schemas list every field the handlers decode.
+	spec := doc.Components.Schemas["ProcessSpec"].Properties
+	specType := reflect.TypeOf(processSpec{})
+	for i := 0; i < specType.NumField(); i++ {
+		name, _, _ := strings.Cut(specType.Field(i).Tag.Get("json"), ",")
+		if _, ok := spec[name]; !ok {
+			t.Errorf("expected field %q in the process schema", name)
+		}
+	}
+	if _, ok := doc.Components.Schemas["AddProcessRequest"].Properties["name"]; !ok {
+		t.Error("expected the legacy add schema to include the name")
+	}
+	if _, ok := doc.Components.Schemas["ProcessResponse"].Properties["status"]; !ok {
+		t.Error("expected the process schema to include the status")
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"ExeProcessManager/config"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+func (api *ProcessAPI) listKeys(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, api.Keys.List())
+}
+
+// mintKeyRequest is the body of the mint key route.
+type mintKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	Processes []string   `json:"processes"`  // names or label selectors, all processes if empty
+	ExpiresAt *time.Time `json:"expires_at"` // never expires if absent
+}
+
+// mintKey creates a key and returns it with its secret, which is not stored
+// and cannot be shown again.
+func (api *ProcessAPI) mintKey(w http.ResponseWriter, r *http.Request) {
+	var req mintKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	secret, key, err := api.Keys.Mint(req.Name, req.Scopes, req.Processes, req.ExpiresAt)
+	if err != nil {
+		respondWithKeyError(w, err)
+		return
+	}
+	api.Logger.Info("api key minted", "key", key.Name, "scopes", key.Scopes, "by", requestKey(r).Name)
+	key.Key = secret
+	respondWithJSON(w, http.StatusCreated, key)
+}
+
+func (api *ProcessAPI) revokeKey(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := api.Keys.Revoke(name); err != nil {
+		respondWithKeyError(w, err)
+		return
+	}
+	api.Logger.Info("api key revoked", "key", name, "by", requestKey(r).Name)
+	respondWithJSON(w, http.StatusOK, messageResponse{Message: "api key revoked"})
+}
+
+// respondWithKeyError writes an error returned by the key store with the
+// status and code of its kind.
+func respondWithKeyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, config.ErrKeyInvalid):
+		respondWithCode(w, http.StatusBadRequest, codeInvalidSpec, err.Error())
+	case errors.Is(err, config.ErrKeyNotFound):
+		respondWithCode(w, http.StatusNotFound, codeNotFound, err.Error())
+	case errors.Is(err, config.ErrKeyExists):
+		respondWithCode(w, http.StatusConflict, codeAlreadyExists, err.Error())
+	case errors.Is(err, config.ErrKeyReadOnly):
+		respondWithCode(w, http.StatusConflict, codeReadOnly, err.Error())
+	default:
+		respondWithCode(w, http.StatusInternalServerError, codeInternal, err.Error())
+	}
+}
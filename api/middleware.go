@@ -1,8 +1,13 @@
 package api
 
 import (
-	"log/slog"
+	"ExeProcessManager/config"
+	"ExeProcessManager/process"
+	"context"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 )
 
 // logRequests is a middleware that logs every incoming request.
@@ -26,23 +31,99 @@ func (api *ProcessAPI) authMiddleware(next http.Handler) http.Handler {
 		}
 
 		// Check if the provided key is valid
-		if !api.isKeyValid(apiKey) {
+		key := api.Keys.Authenticate(apiKey)
+		if key == nil {
 			api.Logger.Warn("Invalid API key provided", "remote_addr", r.RemoteAddr)
 			respondWithError(w, http.StatusForbidden, "Invalid API Key")
 			return
 		}
+		if key.Expired(time.Now()) {
+			api.Logger.Warn("expired API key provided", "key", key.Name, "remote_addr", r.RemoteAddr)
+			respondWithCode(w, http.StatusForbidden, codeKeyExpired, "API key '"+key.Name+"' has expired")
+			return
+		}
 
-		// If the key is valid, proceed to the next handler
-		next.ServeHTTP(w, r)
+		// If the key is valid, proceed to the next handler, which checks
+		// what the key may do on the route
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyContext{}, key)))
 	})
 }
 
-// isKeyValid checks if a given key exists in the configured list of API keys.
-func (api *ProcessAPI) isKeyValid(providedKey string) bool {
-	for _, validKey := range api.Config.ApiKeys {
-		if providedKey == validKey {
+// apiKeyContext is the context key of the API key of a request.
+type apiKeyContext struct{}
+
+// requestKey returns the API key the request was authenticated with.
+func requestKey(r *http.Request) *config.APIKey {
+	key, _ := r.Context().Value(apiKeyContext{}).(*config.APIKey)
+	return key
+}
+
+// authorize wraps the handler of a route so it only runs for keys with the
+// scope of the route. A key limited to some processes may use a route about
+// a process only for one of them, and other routes only to read; lists
+// leave out the processes it may not see.
+func (api *ProcessAPI) authorize(rt route) http.HandlerFunc {
+	processRoute := strings.Contains(rt.pattern, "/processes/{name}")
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := requestKey(r)
+		if key == nil {
+			respondWithError(w, http.StatusUnauthorized, "API Key is missing")
+			return
+		}
+		if !key.HasScope(rt.scope) {
+			api.Logger.Warn("API key lacks the scope of the route", "key", key.Name, "scope", rt.scope, "method", r.Method, "path", r.URL.Path)
+			respondWithError(w, http.StatusForbidden, "API key '"+key.Name+"' lacks the "+rt.scope+" scope")
+			return
+		}
+		if key.Restricted() {
+			allowed := rt.scope == config.ScopeRead
+			if processRoute {
+				allowed = api.permitted(r, r.PathValue("name"))
+			}
+			if !allowed {
+				api.Logger.Warn("API key is not allowed to use the route", "key", key.Name, "method", r.Method, "path", r.URL.Path)
+				respondWithError(w, http.StatusForbidden, "API key '"+key.Name+"' is limited to other processes")
+				return
+			}
+		}
+		rt.handler(w, r)
+	}
+}
+
+// permitted reports whether the key of the request may use the named
+// process: it is unrestricted, lists the name, or has a label selector the
+// process matches.
+func (api *ProcessAPI) permitted(r *http.Request, name string) bool {
+	key := requestKey(r)
+	if key == nil {
+		return false
+	}
+	if !key.Restricted() || slices.Contains(key.Processes, name) {
+		return true
+	}
+	proc, err := api.Manager.GetProcessByName(name)
+	if err != nil {
+		return false
+	}
+	labels := proc.Snapshot().Labels
+	for _, entry := range key.Processes {
+		if strings.Contains(entry, "=") && process.MatchLabels(entry, labels) {
 			return true
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
+
+// deprecated marks the responses of a legacy route with a Deprecation header
+// and links its successor, which is the same path under /v1 if none is given.
+func deprecated(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link := successor
+		if link == "" {
+			link = apiVersion + r.URL.Path
+		}
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+link+">; rel=\"successor-version\"")
+		next(w, r)
+	}
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"ExeProcessManager/process"
+	"net/http"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// openAPIVersion is the version of the OpenAPI specification the document
+// follows.
+const openAPIVersion = "3.0.3"
+
+// openAPI serves the OpenAPI document of the API.
+func (api *ProcessAPI) openAPI(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, api.openAPIDocument())
+}
+
+// openAPIDocument describes every route of the API. Paths, parameters and
+// schemas are derived from the route table and the Go types the handlers
+// decode and encode, so the document follows the code.
+func (api *ProcessAPI) openAPIDocument() map[string]any {
+	schemas := newSchemaSet()
+	paths := map[string]map[string]any{}
+
+	for _, r := range api.routes() {
+		method, urlPath, _ := strings.Cut(r.pattern, " ")
+
+		var params []any
+		for _, segment := range strings.Split(urlPath, "/") {
+			if name, ok := strings.CutPrefix(segment, "{"); ok {
+				params = append(params, map[string]any{
+					"name": strings.TrimSuffix(name, "}"), "in": "path", "required": true,
+					"schema": map[string]any{"type": "string"},
+				})
+			}
+		}
+		for _, q := range r.query {
+			params = append(params, map[string]any{
+				"name": q.name, "in": "query", "description": q.description,
+				"schema": map[string]any{"type": q.kind},
+			})
+		}
+
+		status := r.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		operation := map[string]any{
+			"operationId": r.id,
+			"summary":     r.summary,
+			"description": "Requires an API key with the " + r.scope + " scope.",
+			"responses": map[string]any{
+				strconv.Itoa(status): jsonContent(http.StatusText(status), schemas.of(reflect.TypeOf(r.response))),
+				"default":            jsonContent("Error", schemas.of(reflect.TypeOf(errorResponse{}))),
+			},
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if r.request != nil {
+			body := jsonContent("", schemas.of(reflect.TypeOf(r.request)))
+			delete(body, "description")
+			body["required"] = !r.bodyOptional
+			operation["requestBody"] = body
+		}
+		if r.deprecated {
+			operation["deprecated"] = true
+		}
+
+		if paths[urlPath] == nil {
+			paths[urlPath] = map[string]any{}
+		}
+		paths[urlPath][strings.ToLower(method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]any{
+			"title":   "ExeProcessManager API",
+			"version": strings.TrimPrefix(apiVersion, "/"),
+		},
+		"security": []any{map[string]any{"apiKey": []string{}}},
+		"paths":    paths,
+		"components": map[string]any{
+			"securitySchemes": map[string]any{
+				"apiKey": map[string]any{"type": "apiKey", "in": "header", "name": "X-API-KEY",
+					"description": "A key with the read, control or admin scope, each including the ones before it. Keys limited to some processes can only read the other routes, and lists leave out the processes they may not see."},
+			},
+			"schemas": schemas.named,
+		},
+	}
+}
+
+// jsonContent describes a JSON body with the given schema.
+func jsonContent(description string, schema map[string]any) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content":     map[string]any{"application/json": map[string]any{"schema": schema}},
+	}
+}
+
+// schemaSet builds JSON schemas for Go types the way encoding/json encodes
+// them. Named structs become components referenced by name.
+type schemaSet struct {
+	named map[string]any
+	types map[string]reflect.Type
+}
+
+func newSchemaSet() *schemaSet {
+	return &schemaSet{named: map[string]any{}, types: map[string]reflect.Type{}}
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(process.Duration(0))
+)
+
+// of returns the schema of t.
+func (s *schemaSet) of(t reflect.Type) map[string]any {
+	if t == nil {
+		return map[string]any{}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case durationType:
+		return map[string]any{"type": "string", "description": "a duration such as 1m30s", "example": "30s"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": s.of(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		name := s.name(t)
+		if _, ok := s.named[name]; !ok {
+			s.named[name] = nil // Reserved, so recursive types end here
+			s.named[name] = s.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	default:
+		return map[string]any{}
+	}
+}
+
+// name returns the component name of a named struct type.
+func (s *schemaSet) name(t reflect.Type) string {
+	r, size := utf8.DecodeRuneInString(t.Name())
+	name := string(unicode.ToUpper(r)) + t.Name()[size:]
+	if other, ok := s.types[name]; ok && other != t {
+		pkg := path.Base(t.PkgPath())
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	s.types[name] = t
+	return name
+}
+
+// object returns the schema of a struct, with the fields of embedded structs
+// promoted as encoding/json does.
+func (s *schemaSet) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	s.addFields(t, properties)
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+func (s *schemaSet) addFields(t reflect.Type, properties map[string]any) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			s.addFields(fieldType, properties)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = s.of(field.Type)
+	}
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"ExeProcessManager/process"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+func (api *ProcessAPI) getProcesses(w http.ResponseWriter, r *http.Request) {
+	response := []processResponse{}
+	for _, p := range api.Manager.List() {
+		if api.permitted(r, p.Name) {
+			response = append(response, newProcessResponse(p))
+		}
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (api *ProcessAPI) getProcess(w http.ResponseWriter, r *http.Request) {
+	proc, err := api.Manager.GetProcessByName(r.PathValue("name"))
+	if err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newProcessResponse(proc))
+}
+
+// putProcess adds the process, or replaces the configuration of an existing
+// one with the request body.
+func (api *ProcessAPI) putProcess(w http.ResponseWriter, r *http.Request) {
+	var spec processSpec
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := spec.validate(); err != nil {
+		respondWithCode(w, http.StatusBadRequest, codeInvalidSpec, err.Error())
+		return
+	}
+
+	name := r.PathValue("name")
+	proc, err := api.Manager.GetProcessByName(name)
+	if err != nil {
+		proc, err = api.Manager.AddProcess(name, spec.Path, spec.Schedul, spec.options()...)
+		if err != nil {
+			respondWithProcessError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusCreated, newProcessResponse(proc))
+		return
+	}
+
+	if err := proc.Reconfigure(spec.Path, spec.Schedul, spec.options()...); err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newProcessResponse(proc))
+}
+
+// patchProcess merges the request body into the configuration of a
+// process: fields it leaves out keep their value, and null clears a field.
+func (api *ProcessAPI) patchProcess(w http.ResponseWriter, r *http.Request) {
+	proc, err := api.Manager.GetProcessByName(r.PathValue("name"))
+	if err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+
+	// Going through JSON gives the patch a deep copy to write into.
+	current, err := json.Marshal(specOf(proc.Snapshot()))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var spec processSpec
+	if err := json.Unmarshal(current, &spec); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := spec.validate(); err != nil {
+		respondWithCode(w, http.StatusBadRequest, codeInvalidSpec, err.Error())
+		return
+	}
+
+	if err := proc.Reconfigure(spec.Path, spec.Schedul, spec.options()...); err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newProcessResponse(proc))
+}
+
+// deleteProcess stops the process if needed and removes it from the manager.
+func (api *ProcessAPI) deleteProcess(w http.ResponseWriter, r *http.Request) {
+	if err := api.Manager.RemoveProcess(r.PathValue("name")); err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, messageResponse{Message: "process removed"})
+}
+
+func (api *ProcessAPI) startAction(w http.ResponseWriter, r *http.Request) {
+	var req startRequest
+	if err := decodeOptionalBody(r, &req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	opts, err := req.options()
+	if err != nil {
+		respondWithCode(w, http.StatusBadRequest, codeInvalidSpec, err.Error())
+		return
+	}
+	proc, err := api.Manager.GetProcessByName(r.PathValue("name"))
+	if err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+
+	if err := proc.StartWith(opts); err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newProcessResponse(proc))
+}
+
+func (api *ProcessAPI) stopAction(w http.ResponseWriter, r *http.Request) {
+	var req stopRequest
+	if err := decodeOptionalBody(r, &req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	proc, err := api.Manager.GetProcessByName(r.PathValue("name"))
+	if err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+
+	if err := proc.StopWith(req.Signal, time.Duration(req.Timeout)); err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newProcessResponse(proc))
+}
+
+// restartAction stops the process if it is running and starts it again
+// with the options of the request.
+func (api *ProcessAPI) restartAction(w http.ResponseWriter, r *http.Request) {
+	var req startRequest
+	if err := decodeOptionalBody(r, &req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	opts, err := req.options()
+	if err != nil {
+		respondWithCode(w, http.StatusBadRequest, codeInvalidSpec, err.Error())
+		return
+	}
+	proc, err := api.Manager.GetProcessByName(r.PathValue("name"))
+	if err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+
+	if proc.Snapshot().Schedul == 1 {
+		respondWithCode(w, http.StatusConflict, codeScheduledOnly, "process '"+proc.Name+"' is scheduled and cannot be restarted manually")
+		return
+	}
+	if proc.IsRunning() {
+		if err := proc.Stop(); err != nil {
+			respondWithProcessError(w, err)
+			return
+		}
+	}
+	if err := proc.StartWith(opts); err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newProcessResponse(proc))
+}
+
+// signalRequest is the body of the signal action.
+type signalRequest struct {
+	Signal string `json:"signal"` // a name such as SIGHUP or a number
+}
+
+// signalAction sends a signal to a running process without stopping it.
+func (api *ProcessAPI) signalAction(w http.ResponseWriter, r *http.Request) {
+	var req signalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if _, _, err := process.ParseSignal(req.Signal); err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	proc, err := api.Manager.GetProcessByName(r.PathValue("name"))
+	if err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+
+	if err := proc.Signal(req.Signal); err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newProcessResponse(proc))
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"ExeProcessManager/process"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ruleResponse is a timing rule as returned by the API, with its name and
+// next fire time.
+type ruleResponse struct {
+	Name string `json:"name"`
+	*process.TimingRule
+	NextRun *time.Time `json:"next_run,omitempty"` // absent for fixed-delay rules and rules without further occurrences
+}
+
+func newRuleResponse(name string, rule *process.TimingRule, now time.Time) ruleResponse {
+	response := ruleResponse{Name: name, TimingRule: rule}
+	if next, ok := rule.NextFire(now); ok {
+		response.NextRun = &next
+	}
+	return response
+}
+
+func (api *ProcessAPI) listRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := api.Manager.TimingRules()
+	if err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+
+	now := time.Now()
+	response := make([]ruleResponse, 0, len(rules))
+	for name, rule := range rules {
+		response = append(response, newRuleResponse(name, rule, now))
+	}
+	slices.SortFunc(response, func(a, b ruleResponse) int { return strings.Compare(a.Name, b.Name) })
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// createRuleRequest is the body of the create rule route.
+type createRuleRequest struct {
+	Name        string               `json:"name"`
+	Schedule    string               `json:"schedule"` // same formats as the createrule command
+	WindowStart *time.Time           `json:"window_start"`
+	WindowEnd   *time.Time           `json:"window_end"`
+	TimeZone    string               `json:"time_zone"`
+	DSTGap      string               `json:"dst_gap"`
+	DSTOverlap  string               `json:"dst_overlap"`
+	Misfire     string               `json:"misfire"`
+	Concurrency string               `json:"concurrency"`
+	Timeout     process.Duration     `json:"timeout"`
+	Retry       *process.RetryPolicy `json:"retry"`
+}
+
+func (api *ProcessAPI) createRule(w http.ResponseWriter, r *http.Request) {
+	var req createRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Name == "" || req.Schedule == "" {
+		respondWithCode(w, http.StatusBadRequest, codeInvalidSpec, "name and schedule are required")
+		return
+	}
+
+	var opts []process.RuleOption
+	if req.WindowStart != nil || req.WindowEnd != nil {
+		var start, end time.Time
+		if req.WindowStart != nil {
+			start = *req.WindowStart
+		}
+		if req.WindowEnd != nil {
+			end = *req.WindowEnd
+		}
+		opts = append(opts, process.WithWindow(start, end))
+	}
+	if req.TimeZone != "" {
+		opts = append(opts, process.WithTimeZone(req.TimeZone))
+	}
+	if req.DSTGap != "" || req.DSTOverlap != "" {
+		opts = append(opts, process.WithDSTPolicy(req.DSTGap, req.DSTOverlap))
+	}
+	if req.Misfire != "" {
+		opts = append(opts, process.WithMisfirePolicy(req.Misfire))
+	}
+	if req.Concurrency != "" {
+		opts = append(opts, process.WithConcurrencyPolicy(req.Concurrency))
+	}
+	if req.Timeout != 0 {
+		opts = append(opts, process.WithTimeout(time.Duration(req.Timeout)))
+	}
+	if req.Retry != nil {
+		opts = append(opts, process.WithRetryPolicy(*req.Retry))
+	}
+
+	if err := api.Manager.CreateTimingRule(req.Name, req.Schedule, opts...); err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	rule, err := api.Manager.TimingRule(req.Name)
+	if err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, newRuleResponse(req.Name, rule, time.Now()))
+}
+
+func (api *ProcessAPI) getRule(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	rule, err := api.Manager.TimingRule(name)
+	if err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newRuleResponse(name, rule, time.Now()))
+}
+
+func (api *ProcessAPI) deleteRule(w http.ResponseWriter, r *http.Request) {
+	if err := api.Manager.DeleteTimingRule(r.PathValue("name")); err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, messageResponse{Message: "timing rule deleted"})
+}
+
+// setJobRequest is the body of the set job route.
+type setJobRequest struct {
+	Rule string `json:"rule"`
+}
+
+// setJob attaches a timing rule to a process as its job.
+func (api *ProcessAPI) setJob(w http.ResponseWriter, r *http.Request) {
+	var req setJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	proc, err := api.Manager.GetProcessByName(r.PathValue("name"))
+	if err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	if err := proc.SetJob(req.Rule); err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, messageResponse{Message: "job set"})
+}
+
+// unsetJob cancels the job of a process and detaches its timing rule.
+func (api *ProcessAPI) unsetJob(w http.ResponseWriter, r *http.Request) {
+	proc, err := api.Manager.GetProcessByName(r.PathValue("name"))
+	if err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	if err := proc.UnsetJob(); err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, messageResponse{Message: "job unset"})
+}
+
+// startJob arms the job of a process, like the startjob command.
+func (api *ProcessAPI) startJob(w http.ResponseWriter, r *http.Request) {
+	proc, err := api.Manager.GetProcessByName(r.PathValue("name"))
+	if err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	if err := proc.StartJob(); err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, messageResponse{Message: "job started"})
+}
+
+// cancelJob disarms the pending occurrences of a job, like the canceljob
+// command.
+func (api *ProcessAPI) cancelJob(w http.ResponseWriter, r *http.Request) {
+	proc, err := api.Manager.GetProcessByName(r.PathValue("name"))
+	if err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	if err := proc.CancelJob(); err != nil {
+		respondWithProcessError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, messageResponse{Message: "job cancelled"})
+}
+
+// schedule lists the upcoming fire times of all jobs, soonest first.
+func (api *ProcessAPI) schedule(w http.ResponseWriter, r *http.Request) {
+	jobs := []process.ScheduledJob{}
+	for _, job := range api.Manager.Upcoming() {
+		if api.permitted(r, job.Process) {
+			jobs = append(jobs, job)
+		}
+	}
+	respondWithJSON(w, http.StatusOK, jobs)
+}
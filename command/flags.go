@@ -0,0 +1,109 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// flagSet holds "--key=value" options of a command. An option may be given
+// more than once; bare "--key" options have the value "true".
+type flagSet map[string][]string
+
+// Get returns the last value given for key.
+func (f flagSet) Get(key string) (string, bool) {
+	values := f[key]
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+// Has reports whether key was given at all.
+func (f flagSet) Has(key string) bool {
+	return len(f[key]) > 0
+}
+
+// splitFlags separates options from positional parameters.
+func splitFlags(params []string) ([]string, flagSet) {
+	positional := make([]string, 0, len(params))
+	flags := make(flagSet)
+	for _, param := range params {
+		if !strings.HasPrefix(param, "--") || len(param) == 2 {
+			positional = append(positional, param)
+			continue
+		}
+		key, value, found := strings.Cut(param[2:], "=")
+		if !found {
+			value = "true"
+		}
+		flags[key] = append(flags[key], value)
+	}
+	return positional, flags
+}
+
+// durationFlag parses a duration option, returning zero if it is not set.
+func durationFlag(flags flagSet, key string) (time.Duration, error) {
+	value, ok := flags.Get(key)
+	if !ok {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for --%s: %w", key, err)
+	}
+	return d, nil
+}
+
+// splitCommandLine splits a command line into words the way a POSIX shell
+// would, without expansions: single quotes keep their content literally,
+// double quotes allow \" and \\ escapes, and a backslash outside quotes
+// escapes the next character.
+func splitCommandLine(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) && (line[i+1] == '"' || line[i+1] == '\\') {
+					i++
+				}
+				word.WriteByte(line[i])
+			}
+			if i >= len(line) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+		case c == '\\' && i+1 < len(line):
+			i++
+			word.WriteByte(line[i])
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -1,6 +1,7 @@
 package command
 
 import (
+	"ExeProcessManager/config"
 	"ExeProcessManager/process"
 	"bufio"
 	"context"
@@ -9,27 +10,30 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // CLI handles the command-line interface.
 type CLI struct {
 	manager *process.ProcessManager
+	keys    *config.KeyStore
 	logger  *slog.Logger
+	input   *bufio.Reader // Shared by the REPL and commands that wait for Enter
 }
 
 // NewCLI creates a new CLI handler.
-func NewCLI(manager *process.ProcessManager, logger *slog.Logger) *CLI {
+func NewCLI(manager *process.ProcessManager, keys *config.KeyStore, logger *slog.Logger) *CLI {
 	return &CLI{
 		manager: manager,
+		keys:    keys,
 		logger:  logger,
+		input:   bufio.NewReader(os.Stdin),
 	}
 }
 
 // Start begins the CLI read-eval-print loop (REPL).
 func (cli *CLI) Start(ctx context.Context) {
 	cli.logger.Info("CLI started. Type 'help' for commands.")
-	reader := bufio.NewReader(os.Stdin)
-
 	for {
 		select {
 		case <-ctx.Done(): // Check if a shutdown has been requested
@@ -37,7 +41,7 @@ func (cli *CLI) Start(ctx context.Context) {
 			return
 		default:
 			fmt.Print(">>> ")
-			cmd, err := reader.ReadString('\n')
+			cmd, err := cli.input.ReadString('\n')
 			if err != nil {
 				// This can happen if Stdin is closed, e.g., during shutdown
 				cli.logger.Debug("CLI reader error", "error", err)
@@ -49,7 +53,11 @@ func (cli *CLI) Start(ctx context.Context) {
 }
 
 func (cli *CLI) handleCommand(cmd string) {
-	args := strings.Fields(cmd)
+	args, err := splitCommandLine(cmd)
+	if err != nil {
+		fmt.Println("Error:", err.Error())
+		return
+	}
 	if len(args) == 0 {
 		return
 	}
@@ -74,7 +82,34 @@ func (cli *CLI) handleCommand(cmd string) {
 		cli.listProcesses()
 	case "remove":
 		cli.removeProcess(params)
-	// Add other cases for scheduling here...
+	case "tree":
+		cli.showTree(params)
+	case "logs":
+		cli.showLogs(params)
+	case "history":
+		cli.showHistory(params)
+	case "createrule":
+		cli.createRule(params)
+	case "rules":
+		cli.listRules()
+	case "deleterule":
+		cli.deleteRule(params)
+	case "setjob":
+		cli.setJob(params)
+	case "unsetjob":
+		cli.unsetJob(params)
+	case "startjob":
+		cli.startJob(params)
+	case "canceljob":
+		cli.cancelJob(params)
+	case "mintkey":
+		cli.mintKey(params)
+	case "keys":
+		cli.listKeys()
+	case "revokekey":
+		cli.revokeKey(params)
+	case "hashkey":
+		cli.hashKey(params)
 	default:
 		fmt.Println("Unknown command. Use 'help' for a list of commands.")
 	}
@@ -83,8 +118,16 @@ func (cli *CLI) handleCommand(cmd string) {
 // --- Command Implementations ---
 
 func (cli *CLI) addProcess(params []string) {
+	params, flags := splitFlags(params)
 	if len(params) < 3 {
-		fmt.Println("Usage: add <name> <path> <schedul (0=manual, 1=auto)>")
+		fmt.Println("Usage: add <name> <path> <schedul (0=manual, 1=auto)> [options]")
+		fmt.Println("Options: --restart=<never|on-failure|always> --max-retries=N --backoff=D --backoff-max=D --stable-after=D")
+		fmt.Println("         --stop-signal=SIGTERM --stop-timeout=D")
+		fmt.Println("         --arg=VALUE --env=KEY=VALUE --env-file=PATH --dir=PATH --no-inherit-env")
+		fmt.Println("         --label=KEY=VALUE")
+		fmt.Println("         --user=NAME --group=NAME --groups=NAME,NAME")
+		fmt.Println("         --max-open-files=N --max-procs=N --core-size=SIZE --address-space=SIZE")
+		fmt.Println("         --memory-max=SIZE --cpu-max=\"QUOTA PERIOD\" --pids-max=N")
 		return
 	}
 	name, path := params[0], params[1]
@@ -94,7 +137,13 @@ func (cli *CLI) addProcess(params []string) {
 		return
 	}
 
-	if _, err := cli.manager.AddProcess(name, path, schedul); err != nil {
+	opts, err := processOptions(flags)
+	if err != nil {
+		fmt.Println("Error:", err.Error())
+		return
+	}
+
+	if _, err := cli.manager.AddProcess(name, path, schedul, opts...); err != nil {
 		cli.logger.Error("failed to add process", "error", err)
 		fmt.Println("Error:", err.Error())
 		return
@@ -102,9 +151,169 @@ func (cli *CLI) addProcess(params []string) {
 	fmt.Printf("Process '%s' added successfully.\n", name)
 }
 
+// processOptions turns the options of the 'add' command into process options.
+func processOptions(flags flagSet) ([]process.ProcessOption, error) {
+	var opts []process.ProcessOption
+
+	if mode, ok := flags.Get("restart"); ok {
+		policy := process.RestartPolicy{Mode: mode}
+		if value, ok := flags.Get("max-retries"); ok {
+			retries, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for --max-retries: %w", err)
+			}
+			policy.MaxRetries = &retries
+		}
+		durations := map[string]*process.Duration{
+			"backoff":      &policy.BackoffBase,
+			"backoff-max":  &policy.BackoffCap,
+			"stable-after": &policy.StableAfter,
+		}
+		for key, target := range durations {
+			d, err := durationFlag(flags, key)
+			if err != nil {
+				return nil, err
+			}
+			*target = process.Duration(d)
+		}
+		opts = append(opts, process.WithRestartPolicy(policy))
+	} else {
+		// The tuning options mean nothing without a policy to tune.
+		for _, key := range []string{"max-retries", "backoff", "backoff-max", "stable-after"} {
+			if flags.Has(key) {
+				return nil, fmt.Errorf("--%s requires --restart", key)
+			}
+		}
+	}
+
+	if signal, ok := flags.Get("stop-signal"); ok {
+		opts = append(opts, process.WithStopSignal(signal))
+	}
+	if flags.Has("stop-timeout") {
+		timeout, err := durationFlag(flags, "stop-timeout")
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, process.WithStopTimeout(timeout))
+	}
+
+	if flags.Has("arg") {
+		opts = append(opts, process.WithArgs(flags["arg"]...))
+	}
+	if flags.Has("env") {
+		env := make(map[string]string)
+		for _, entry := range flags["env"] {
+			key, value, ok := strings.Cut(entry, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid value for --env, expected KEY=VALUE: %s", entry)
+			}
+			env[key] = value
+		}
+		opts = append(opts, process.WithEnv(env))
+	}
+	if flags.Has("label") {
+		labels := make(map[string]string)
+		for _, entry := range flags["label"] {
+			key, value, ok := strings.Cut(entry, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid value for --label, expected KEY=VALUE: %s", entry)
+			}
+			labels[key] = value
+		}
+		opts = append(opts, process.WithLabels(labels))
+	}
+	if flags.Has("env-file") {
+		opts = append(opts, process.WithEnvFiles(flags["env-file"]...))
+	}
+	if dir, ok := flags.Get("dir"); ok {
+		opts = append(opts, process.WithDir(dir))
+	}
+	if flags.Has("no-inherit-env") {
+		opts = append(opts, process.WithInheritEnv(false))
+	}
+
+	if name, ok := flags.Get("user"); ok {
+		opts = append(opts, process.WithUser(name))
+	}
+	if name, ok := flags.Get("group"); ok {
+		opts = append(opts, process.WithGroup(name))
+	}
+	if groups, ok := flags.Get("groups"); ok {
+		opts = append(opts, process.WithSupplementaryGroups(strings.Split(groups, ",")...))
+	}
+
+	limits, err := limitsFlags(flags)
+	if err != nil {
+		return nil, err
+	}
+	if limits != nil {
+		opts = append(opts, process.WithLimits(*limits))
+	}
+
+	return opts, nil
+}
+
+// limitsFlags collects the resource limit options of the 'add' command,
+// returning nil if none is given.
+func limitsFlags(flags flagSet) (*process.Limits, error) {
+	var limits process.Limits
+	set := false
+
+	rlimits := map[string]**uint64{
+		"max-open-files": &limits.OpenFiles,
+		"max-procs":      &limits.Processes,
+		"core-size":      &limits.CoreSize,
+		"address-space":  &limits.AddressSpace,
+	}
+	for key, target := range rlimits {
+		value, ok := flags.Get(key)
+		if !ok {
+			continue
+		}
+		n, err := process.ParseByteSize(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for --%s: %w", key, err)
+		}
+		*target = &n
+		set = true
+	}
+
+	cgroup := map[string]*string{
+		"memory-max": &limits.MemoryMax,
+		"cpu-max":    &limits.CPUMax,
+		"pids-max":   &limits.PidsMax,
+	}
+	for key, target := range cgroup {
+		if value, ok := flags.Get(key); ok {
+			*target = value
+			set = true
+		}
+	}
+
+	if !set {
+		return nil, nil
+	}
+	return &limits, nil
+}
+
 func (cli *CLI) startProcess(params []string) {
+	// Options go before the name; everything after it is passed to the process.
+	var options []string
+	for len(params) > 0 && strings.HasPrefix(params[0], "--") {
+		options, params = append(options, params[0]), params[1:]
+	}
+	_, flags := splitFlags(options)
 	if len(params) < 1 {
-		fmt.Println("Usage: start <process_name> [args...]")
+		fmt.Println("Usage: start [--append] [--timeout=D] <process_name> [args...]")
+		return
+	}
+	timeout, err := durationFlag(flags, "timeout")
+	if err != nil {
+		fmt.Println("Error:", err.Error())
+		return
+	}
+	if timeout < 0 {
+		fmt.Println("Error: --timeout must not be negative")
 		return
 	}
 	name := params[0]
@@ -114,16 +323,26 @@ func (cli *CLI) startProcess(params []string) {
 		return
 	}
 
-	if err := proc.Start(params[1:]...); err != nil {
+	opts := process.StartOptions{Args: params[1:], Timeout: timeout}
+	if flags.Has("append") {
+		opts.ArgsMode = process.ArgsAppend
+	}
+	if err := proc.StartWith(opts); err != nil {
 		fmt.Println("Error starting process:", err.Error())
 		return
 	}
-	fmt.Printf("Process '%s' started with PID %d.\n", proc.Name, proc.Pid)
+	fmt.Printf("Process '%s' started with PID %d.\n", proc.Name, proc.Snapshot().Pid)
 }
 
 func (cli *CLI) stopProcess(params []string) {
+	params, flags := splitFlags(params)
 	if len(params) < 1 {
-		fmt.Println("Usage: stop <process_name>")
+		fmt.Println("Usage: stop <process_name> [--signal=SIGTERM] [--timeout=D]")
+		return
+	}
+	timeout, err := durationFlag(flags, "timeout")
+	if err != nil {
+		fmt.Println("Error:", err.Error())
 		return
 	}
 	name := params[0]
@@ -133,7 +352,8 @@ func (cli *CLI) stopProcess(params []string) {
 		return
 	}
 
-	if err := proc.Stop(); err != nil {
+	signal, _ := flags.Get("signal")
+	if err := proc.StopWith(signal, timeout); err != nil {
 		fmt.Println("Error stopping process:", err.Error())
 		return
 	}
@@ -141,14 +361,15 @@ func (cli *CLI) stopProcess(params []string) {
 }
 
 func (cli *CLI) listProcesses() {
-	processes := cli.manager.Processes
+	processes := cli.manager.List()
 	if len(processes) == 0 {
 		fmt.Println("No processes are being managed.")
 		return
 	}
 	fmt.Println("--- Managed Processes ---")
 	for _, p := range processes {
-		fmt.Printf("Name: %-15s | PID: %-7d | Status: %-10s | Schedule: %d\n", p.Name, p.Pid, p.GetStatus(), p.Schedul)
+		snap := p.Snapshot()
+		fmt.Printf("Name: %-15s | PID: %-7d | Status: %-10s | Schedule: %d\n", snap.Name, snap.Pid, p.GetStatus(), snap.Schedul)
 	}
 }
 
@@ -158,18 +379,55 @@ func (cli *CLI) showStatus(params []string) {
 		return
 	}
 	name := params[0]
-	proc, err := cli.manager.GetProcessByName(name)
+	p, err := cli.manager.GetProcessByName(name)
 	if err != nil {
 		fmt.Println("Error:", err.Error())
 		return
 	}
+	// The exit watcher updates the process concurrently, so read a copy.
+	proc, status := p.Snapshot(), p.GetStatus()
 	fmt.Printf("--- Status for '%s' ---\n", name)
 	fmt.Printf("  PID: %d\n", proc.Pid)
 	fmt.Printf("  Path: %s\n", proc.Path)
-	fmt.Printf("  Status: %s\n", proc.GetStatus())
+	if len(proc.Labels) > 0 {
+		fmt.Printf("  Labels: %v\n", proc.Labels)
+	}
+	if len(proc.Args) > 0 {
+		fmt.Printf("  Default Args: %q\n", proc.Args)
+	}
+	if proc.Dir != "" {
+		fmt.Printf("  Working Dir: %s\n", proc.Dir)
+	}
+	if proc.User != "" || proc.Group != "" {
+		fmt.Printf("  Runs As: user=%s group=%s groups=%v\n", proc.User, proc.Group, proc.SupplementaryGroups)
+	}
+	if len(proc.Env) > 0 || len(proc.EnvFiles) > 0 || !proc.InheritEnv {
+		fmt.Printf("  Environment: %d variables, %d env files, inherit: %t\n", len(proc.Env), len(proc.EnvFiles), proc.InheritEnv)
+	}
+	fmt.Printf("  Status: %s\n", status)
 	fmt.Printf("  Scheduling: %d\n", proc.Schedul)
+	if proc.Restart != nil {
+		fmt.Printf("  Restart Policy: %s (max %d retries)\n", proc.Restart.Mode, *proc.Restart.MaxRetries)
+		fmt.Printf("  Restarts: %d\n", proc.Restarts)
+	}
+	if !proc.EndTime.IsZero() {
+		exit := fmt.Sprintf("code %d", proc.ExitCode)
+		if proc.ExitSignal != "" {
+			exit = "signal " + proc.ExitSignal
+		}
+		if proc.ExitReason != "" {
+			exit += " (" + proc.ExitReason + ")"
+		}
+		fmt.Printf("  Last Exit: %s at %s\n", exit, proc.EndTime.Format(time.RFC1123))
+	}
 	if proc.Timing != nil {
-		fmt.Printf("  Scheduled Time: %s\n", proc.Timing.ScheduleTime.Format(time.RFC1123))
+		fmt.Printf("  Schedule: %s\n", proc.Timing)
+		if proc.JobRule != "" {
+			fmt.Printf("  Job Rule: %s\n", proc.JobRule)
+		}
+		if !proc.NextRun.IsZero() {
+			fmt.Printf("  Next Run: %s\n", proc.NextRun.Format(time.RFC1123))
+		}
 	}
 }
 
@@ -186,18 +444,218 @@ func (cli *CLI) removeProcess(params []string) {
 	fmt.Printf("Process '%s' has been removed.\n", name)
 }
 
+func (cli *CLI) showTree(params []string) {
+	if len(params) < 1 {
+		fmt.Println("Usage: tree <process_name>")
+		return
+	}
+	proc, err := cli.manager.GetProcessByName(params[0])
+	if err != nil {
+		fmt.Println("Error:", err.Error())
+		return
+	}
+	tree, err := proc.Tree()
+	if err != nil {
+		fmt.Println("Error:", err.Error())
+		return
+	}
+	fmt.Printf("--- Process tree for '%s' ---\n", proc.Name)
+	printTree(tree, 0)
+}
+
+// printTree prints a process tree with one indented line per process.
+func printTree(node *process.TreeNode, depth int) {
+	fmt.Printf("%s%d %s [%s]\n", strings.Repeat("  ", depth+1), node.Pid, node.Command, node.State)
+	for _, child := range node.Children {
+		printTree(child, depth+1)
+	}
+}
+
+// defaultHistoryRuns is the number of runs 'history' prints when -n is not given.
+const defaultHistoryRuns = 10
+
+func (cli *CLI) showHistory(params []string) {
+	var name string
+	limit := defaultHistoryRuns
+	for i := 0; i < len(params); i++ {
+		switch param := params[i]; {
+		case param == "-n" && i+1 < len(params):
+			n, err := strconv.Atoi(params[i+1])
+			if err != nil || n < 1 {
+				fmt.Println("Invalid value for -n, must be a positive number.")
+				return
+			}
+			limit = n
+			i++
+		case name == "":
+			name = param
+		}
+	}
+	if name == "" {
+		fmt.Println("Usage: history <process_name> [-n N]")
+		return
+	}
+
+	proc, err := cli.manager.GetProcessByName(name)
+	if err != nil {
+		fmt.Println("Error:", err.Error())
+		return
+	}
+	runs, err := proc.Runs()
+	if err != nil {
+		fmt.Println("Error:", err.Error())
+		return
+	}
+	if len(runs) == 0 {
+		fmt.Printf("Process '%s' has not run yet.\n", name)
+		return
+	}
+	if len(runs) > limit {
+		runs = runs[len(runs)-limit:]
+	}
+
+	fmt.Printf("--- Runs of '%s' ---\n", name)
+	for _, run := range runs {
+		outcome := run.Status
+		switch {
+		case run.ExitSignal != "":
+			outcome += ", signal " + run.ExitSignal
+		case run.ExitCode != nil:
+			outcome += fmt.Sprintf(", exit code %d", *run.ExitCode)
+		}
+		if run.ExitReason != "" {
+			outcome += " (" + run.ExitReason + ")"
+		}
+		duration := "-"
+		if run.EndTime != nil {
+			duration = time.Duration(run.Duration).Round(time.Millisecond).String()
+		}
+		fmt.Printf("%s | %-8s | %s | %10s | %s\n", run.ID, run.Trigger, run.StartTime.Format(time.RFC1123), duration, outcome)
+	}
+}
+
+// defaultLogLines is the number of lines 'logs' prints when -n is not given.
+const defaultLogLines = 20
+
+func (cli *CLI) showLogs(params []string) {
+	var name, stream string
+	lines, follow := defaultLogLines, false
+	for i := 0; i < len(params); i++ {
+		switch param := params[i]; {
+		case param == "-f":
+			follow = true
+		case param == "-n" && i+1 < len(params):
+			n, err := strconv.Atoi(params[i+1])
+			if err != nil || n < 0 {
+				fmt.Println("Invalid value for -n, must be a non-negative number.")
+				return
+			}
+			lines = n
+			i++
+		case strings.HasPrefix(param, "--stream="):
+			stream = strings.TrimPrefix(param, "--stream=")
+		case name == "":
+			name = param
+		}
+	}
+	if name == "" {
+		fmt.Println("Usage: logs <process_name> [-f] [-n N] [--stream=stdout|stderr]")
+		return
+	}
+
+	proc, err := cli.manager.GetProcessByName(name)
+	if err != nil {
+		fmt.Println("Error:", err.Error())
+		return
+	}
+
+	var live <-chan process.LogLine
+	if follow {
+		var cancel func()
+		live, cancel, err = proc.FollowLogs(stream)
+		if err != nil {
+			fmt.Println("Error:", err.Error())
+			return
+		}
+		defer cancel()
+	}
+
+	history, err := proc.TailLogs(lines, stream)
+	if err != nil {
+		fmt.Println("Error:", err.Error())
+		return
+	}
+	for _, line := range history {
+		fmt.Println(line.String())
+	}
+	if !follow {
+		return
+	}
+
+	fmt.Println("--- Following output, press Enter to stop ---")
+	enter := make(chan struct{})
+	go func() {
+		cli.input.ReadString('\n')
+		close(enter)
+	}()
+	for {
+		select {
+		case <-enter:
+			return
+		case line, open := <-live:
+			if !open {
+				// The reader goroutine still owns the next line of input.
+				fmt.Println("--- Process removed, press Enter to continue ---")
+				<-enter
+				return
+			}
+			fmt.Println(line.String())
+		}
+	}
+}
+
 func showHelp() {
 	fmt.Println("--- ExeProcessManager Help ---")
 	fmt.Println("  help                            - Show this help message")
 	fmt.Println("  list                            - List all managed processes")
 	fmt.Println("  add <name> <path> <schedul>     - Add a new process (0=manual, 1=auto)")
-	fmt.Println("  start <name> [args...]          - Start a manual process by name")
-	fmt.Println("  stop <name>                     - Stop a running process by name")
+	fmt.Println("      [--restart=<never|on-failure|always>] [--max-retries=N]")
+	fmt.Println("      [--backoff=1s] [--backoff-max=1m] [--stable-after=10s]")
+	fmt.Println("      [--stop-signal=SIGTERM] [--stop-timeout=10s]")
+	fmt.Println("      [--arg=VALUE]... [--env=KEY=VALUE]... [--env-file=PATH]... [--dir=PATH] [--no-inherit-env]")
+	fmt.Println("      [--label=KEY=VALUE]...")
+	fmt.Println("      [--user=NAME] [--group=NAME] [--groups=NAME,NAME]")
+	fmt.Println("      [--max-open-files=N] [--max-procs=N] [--core-size=SIZE] [--address-space=SIZE]")
+	fmt.Println("      [--memory-max=SIZE] [--cpu-max=\"QUOTA PERIOD\"] [--pids-max=N]")
+	fmt.Println("  start [--append] [--timeout=D] <name> [args...]")
+	fmt.Println("                                  - Start a manual process; args replace its defaults, or follow them with --append;")
+	fmt.Println("                                    with --timeout the run is stopped once it has taken that long")
+	fmt.Println("  stop <name> [--signal=S] [--timeout=D]")
+	fmt.Println("                                  - Stop a running process by name (SIGKILL after the timeout)")
 	fmt.Println("  status <name>                   - Show detailed status of a process")
 	fmt.Println("  remove <name>                   - Stop and remove a process from management")
+	fmt.Println("  tree <name>                     - Show the PIDs of a running process and its descendants")
+	fmt.Println("  logs <name> [-f] [-n N] [--stream=stdout|stderr]")
+	fmt.Println("                                  - Show captured output; -f follows until Enter is pressed")
+	fmt.Println("  history <name> [-n N]           - Show the last runs of a process with their trigger and outcome")
 	fmt.Println("  exit                            - (Deprecated) Use Ctrl+C to shut down gracefully")
 	fmt.Println("--- Scheduling ---")
-	fmt.Println("  createrule <rule_name> <time>   - Create a timing rule (time is Unix timestamp or RFC1123)")
+	fmt.Println("  createrule <rule_name> <time> [options]")
+	fmt.Println("                                  - Create a timing rule; time is a Unix timestamp, RFC1123 time,")
+	fmt.Println("                                    \"every D\", \"D after previous run finishes\" or a cron expression")
+	fmt.Println("      [--from=TIME] [--until=TIME] [--tz=ZONE] [--dst-gap=<next|skip>] [--dst-overlap=<once|twice>]")
+	fmt.Println("      [--misfire=<run_once|skip|run_all>] [--concurrency=<forbid|allow|replace|queue>] [--timeout=D]")
+	fmt.Println("      [--retries=N] [--retry-backoff=<fixed|exponential>] [--retry-delay=D] [--retry-max-delay=D]")
+	fmt.Println("  rules                           - List timing rules with their next fire time")
+	fmt.Println("  deleterule <rule_name>          - Delete a timing rule that is not set as a job")
 	fmt.Println("  setjob <proc_name> <rule_name>  - Assign a timing rule to a process")
+	fmt.Println("  unsetjob <proc_name>            - Cancel the job of a process and remove its timing rule")
 	fmt.Println("  startjob <proc_name>            - Start a scheduled process (will wait if needed)")
-}
\ No newline at end of file
+	fmt.Println("  canceljob <proc_name>           - Cancel the pending occurrences of a job; a running run continues")
+	fmt.Println("--- API Keys ---")
+	fmt.Println("  mintkey <name> --scopes=<read|control|admin>[,...] [--process=NAME|SELECTOR]... [--expires=D|TIME]")
+	fmt.Println("                                  - Create an API key; it is shown once and only its hash is stored")
+	fmt.Println("  keys                            - List API keys with their scopes and expiry, without secrets")
+	fmt.Println("  revokekey <name>                - Revoke a minted API key immediately")
+	fmt.Println("  hashkey <name> [secret]         - Print a key and its hash, with a new random secret if none is given, for api_keys in config.json")
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 // setupCLITest is a helper to create all components for a CLI test.
@@ -19,7 +20,11 @@ func setupCLITest(t *testing.T) (*CLI, *process.ProcessManager) {
 		ScheduleDir: t.TempDir(),
 	}
 	pm := process.NewProcessManager(logger, cfg)
-	cli := NewCLI(pm, logger)
+	keys, err := config.NewKeyStore(cfg)
+	if err != nil {
+		t.Fatalf("failed to create key store: %v", err)
+	}
+	cli := NewCLI(pm, keys, logger)
 	return cli, pm
 }
 
@@ -58,6 +63,17 @@ func TestCLI_AddCommand(t *testing.T) {
 	if err != nil {
 		t.Errorf("process was not added to manager after 'add' command: %v", err)
 	}
+
+	// Restart tuning without a policy is rejected rather than ignored
+	output = captureOutput(func() {
+		cli.handleCommand("add tuned /usr/bin/top 0 --max-retries=3")
+	})
+	if !strings.Contains(output, "--max-retries requires --restart") {
+		t.Errorf("expected restart tuning without --restart to be rejected: got '%s'", output)
+	}
+	if _, err := manager.GetProcessByName("tuned"); err == nil {
+		t.Error("process was added despite an invalid option")
+	}
 }
 
 // TestCLI_ListCommand tests the 'list' command.
@@ -81,4 +97,168 @@ func TestCLI_ListCommand(t *testing.T) {
 	if !strings.Contains(outputWithProcess, "listed-proc") {
 		t.Errorf("expected process name not found in list output: got '%s'", outputWithProcess)
 	}
-}
\ No newline at end of file
+}
+
+// TestCLI_LogsCommand tests the 'logs' command without follow mode.
+func TestCLI_LogsCommand(t *testing.T) {
+	cli, manager := setupCLITest(t)
+
+	proc, _ := manager.AddProcess("log-proc", "/bin/sh", 0)
+	if err := proc.Start("-c", "echo one; echo two"); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond) // Let the output reach the log
+
+	output := captureOutput(func() {
+		cli.handleCommand("logs log-proc -n 1")
+	})
+	if !strings.Contains(output, "stdout two") || strings.Contains(output, "stdout one") {
+		t.Errorf("expected only the last line, got '%s'", output)
+	}
+}
+
+// TestSplitCommandLine tests shell-like splitting of quoted arguments.
+func TestSplitCommandLine(t *testing.T) {
+	words, err := splitCommandLine(`start job "hello world" 'it''s' a\ b "say \"hi\"" --env="K=v w"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"start", "job", "hello world", "its", "a b", `say "hi"`, "--env=K=v w"}
+	if strings.Join(words, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %q, got %q", want, words)
+	}
+
+	if _, err := splitCommandLine(`start "open`); err == nil {
+		t.Error("unterminated quote was accepted")
+	}
+}
+
+// TestCLI_HistoryCommand tests the 'history' command.
+func TestCLI_HistoryCommand(t *testing.T) {
+	cli, manager := setupCLITest(t)
+
+	proc, _ := manager.AddProcess("hist-proc", "/bin/sh", 0)
+	output := captureOutput(func() {
+		cli.handleCommand("history hist-proc")
+	})
+	if !strings.Contains(output, "has not run yet") {
+		t.Errorf("expected empty history message, got '%s'", output)
+	}
+
+	if err := proc.Start("-c", "exit 2"); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	output = captureOutput(func() {
+		cli.handleCommand("history hist-proc")
+	})
+	if !strings.Contains(output, "manual") || !strings.Contains(output, "failed, exit code 2") {
+		t.Errorf("expected the failed manual run, got '%s'", output)
+	}
+}
+
+// TestCLI_ScheduleCommands tests the rule and job commands.
+func TestCLI_ScheduleCommands(t *testing.T) {
+	cli, manager := setupCLITest(t)
+	proc, _ := manager.AddProcess("nightly", "/bin/true", 1)
+
+	output := captureOutput(func() {
+		cli.handleCommand("createrule every-hour every 1h --concurrency=queue --retries=2 --retry-backoff=exponential")
+		cli.handleCommand("createrule backup 30 2 * * * --tz=Europe/Berlin")
+		cli.handleCommand("createrule broken every 1h --retries=2 --retry-backoff=linear")
+	})
+	if strings.Count(output, "created.") != 2 || !strings.Contains(output, "invalid retry backoff") {
+		t.Fatalf("expected two rules to be created and one rejected, got '%s'", output)
+	}
+	rule, err := manager.TimingRule("every-hour")
+	if err != nil || rule.Concurrency != process.ConcurrencyQueue || rule.Retry == nil || rule.Retry.MaxRetries != 2 {
+		t.Fatalf("expected the rule options to be saved, got %+v (err=%v)", rule, err)
+	}
+
+	output = captureOutput(func() {
+		cli.handleCommand("rules")
+	})
+	if !strings.Contains(output, "backup") || !strings.Contains(output, "cron 30 2 * * * (Europe/Berlin)") || !strings.Contains(output, "every-hour") {
+		t.Errorf("expected both rules to be listed, got '%s'", output)
+	}
+
+	output = captureOutput(func() {
+		cli.handleCommand("setjob nightly every-hour")
+		cli.handleCommand("startjob nightly")
+		cli.handleCommand("deleterule every-hour")
+	})
+	if !strings.Contains(output, "Job of process 'nightly' started.") || !strings.Contains(output, "unset it first") {
+		t.Errorf("expected the job to start and the rule to be kept, got '%s'", output)
+	}
+	if proc.NextRun.IsZero() || !proc.JobActive {
+		t.Fatalf("expected the job to be armed, next run %v", proc.NextRun)
+	}
+
+	output = captureOutput(func() {
+		cli.handleCommand("canceljob nightly")
+		cli.handleCommand("canceljob nightly")
+	})
+	if !strings.Contains(output, "cancelled.") || !strings.Contains(output, "is not active") {
+		t.Errorf("expected the job to be cancelled once, got '%s'", output)
+	}
+	if proc.IsJobDeleted != 1 || !proc.NextRun.IsZero() || proc.JobActive {
+		t.Errorf("expected the job to be marked deleted and disarmed, got deleted=%d next=%v", proc.IsJobDeleted, proc.NextRun)
+	}
+
+	output = captureOutput(func() {
+		cli.handleCommand("unsetjob nightly")
+		cli.handleCommand("deleterule every-hour")
+	})
+	if !strings.Contains(output, "unset.") || !strings.Contains(output, "Timing rule 'every-hour' deleted.") {
+		t.Errorf("expected the job to be unset and the rule deleted, got '%s'", output)
+	}
+	if proc.Timing != nil {
+		t.Errorf("expected the timing rule to be removed from the process")
+	}
+}
+
+func TestCLI_KeyCommands(t *testing.T) {
+	cli, _ := setupCLITest(t)
+
+	output := captureOutput(func() {
+		cli.handleCommand("mintkey deploy --scopes=control --process=web --process=team=deploy --expires=720h")
+		cli.handleCommand("mintkey broken --scopes=root")
+	})
+	if !strings.Contains(output, "API key 'deploy' minted.") || !strings.Contains(output, "epm_") || !strings.Contains(output, "invalid scope") {
+		t.Fatalf("expected one key to be minted and one rejected, got '%s'", output)
+	}
+	secret := output[strings.Index(output, "epm_"):]
+	secret = secret[:strings.IndexByte(secret, '\n')]
+	key := cli.keys.Authenticate(secret)
+	if key == nil || key.Name != "deploy" || len(key.Processes) != 2 || key.ExpiresAt == nil {
+		t.Fatalf("expected the printed key to authenticate with its options, got %+v", key)
+	}
+
+	output = captureOutput(func() {
+		cli.handleCommand("keys")
+	})
+	if !strings.Contains(output, "deploy") || !strings.Contains(output, "web team=deploy") || strings.Contains(output, secret) {
+		t.Errorf("expected the key to be listed without its secret, got '%s'", output)
+	}
+
+	output = captureOutput(func() {
+		cli.handleCommand("revokekey deploy")
+	})
+	if !strings.Contains(output, "API key 'deploy' revoked.") || cli.keys.Authenticate(secret) != nil {
+		t.Errorf("expected the key to be revoked, got '%s'", output)
+	}
+
+	// hashkey prints the key with its name and a hash that config.json
+	// accepts for it.
+	output = captureOutput(func() {
+		cli.handleCommand("hashkey admin my-admin-key")
+	})
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	hashed := config.APIKey{Name: "admin", Hash: lines[len(lines)-1], Scopes: []string{config.ScopeAdmin}}
+	if !strings.Contains(output, "admin.my-admin-key") {
+		t.Errorf("expected the key to start with its name, got '%s'", output)
+	}
+	if err := hashed.Validate(); err != nil || !hashed.Matches("admin.my-admin-key") || hashed.Matches("my-admin-key") {
+		t.Errorf("expected a hash of the key, got '%s' (%v)", output, err)
+	}
+}
@@ -0,0 +1,125 @@
+package command
+
+import (
+	"ExeProcessManager/config"
+	"fmt"
+	"strings"
+	"time"
+)
+
+func (cli *CLI) mintKey(params []string) {
+	params, flags := splitFlags(params)
+	scopes, ok := flags.Get("scopes")
+	if len(params) < 1 || !ok {
+		fmt.Println("Usage: mintkey <name> --scopes=<read|control|admin>[,...] [options]")
+		fmt.Println("Options: --process=NAME|SELECTOR (repeatable, limits the key to these processes)")
+		fmt.Println("         --expires=D|TIME (a duration such as 720h, or an RFC 3339 or RFC1123 time)")
+		return
+	}
+
+	expiresAt, err := expiryFlag(flags)
+	if err != nil {
+		fmt.Println("Error:", err.Error())
+		return
+	}
+	secret, key, err := cli.keys.Mint(params[0], strings.Split(scopes, ","), flags["process"], expiresAt)
+	if err != nil {
+		fmt.Println("Error minting key:", err.Error())
+		return
+	}
+	cli.logger.Info("api key minted", "key", key.Name, "scopes", key.Scopes)
+	fmt.Printf("API key '%s' minted. Store it now, it is not shown again:\n", key.Name)
+	fmt.Println(secret)
+}
+
+// expiryFlag parses the optional --expires option of 'mintkey', returning
+// nil if it is not given.
+func expiryFlag(flags flagSet) (*time.Time, error) {
+	value, ok := flags.Get("expires")
+	if !ok {
+		return nil, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		at := time.Now().Add(d)
+		return &at, nil
+	}
+	at, err := timeFlag(flags, "expires")
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for --expires, expected a duration or a time: %s", value)
+	}
+	return &at, nil
+}
+
+func (cli *CLI) listKeys() {
+	keys := cli.keys.List()
+	if len(keys) == 0 {
+		fmt.Println("No API keys are configured.")
+		return
+	}
+
+	now := time.Now()
+	fmt.Println("--- API Keys ---")
+	for _, key := range keys {
+		expires := "never"
+		if key.ExpiresAt != nil {
+			expires = key.ExpiresAt.Format(time.RFC1123)
+			if key.Expired(now) {
+				expires += " (expired)"
+			}
+		}
+		processes := "all"
+		if key.Restricted() {
+			processes = strings.Join(key.Processes, " ")
+		}
+		fmt.Printf("Name: %-15s | Scopes: %-14s | Processes: %-20s | Expires: %s\n", key.Name, strings.Join(key.Scopes, ","), processes, expires)
+	}
+}
+
+func (cli *CLI) revokeKey(params []string) {
+	if len(params) < 1 {
+		fmt.Println("Usage: revokekey <name>")
+		return
+	}
+	if err := cli.keys.Revoke(params[0]); err != nil {
+		fmt.Println("Error revoking key:", err.Error())
+		return
+	}
+	cli.logger.Info("api key revoked", "key", params[0])
+	fmt.Printf("API key '%s' revoked.\n", params[0])
+}
+
+// hashKey prints the key to send and the hash to give for it in the
+// configuration file. Without a secret, it generates one, so the key is shown
+// only this once.
+func (cli *CLI) hashKey(params []string) {
+	if len(params) < 1 {
+		fmt.Println("Usage: hashkey <name> [secret]")
+		return
+	}
+	secret := ""
+	if len(params) > 1 {
+		secret = params[1]
+	} else {
+		generated, err := config.GenerateKey()
+		if err != nil {
+			fmt.Println("Error:", err.Error())
+			return
+		}
+		secret = generated
+	}
+	key, err := config.NamedKey(params[0], secret)
+	if err != nil {
+		fmt.Println("Error:", err.Error())
+		return
+	}
+	fmt.Println("API key to send. Store it now, it is not shown again:")
+	fmt.Println(key)
+
+	hash, err := config.HashKey(key)
+	if err != nil {
+		fmt.Println("Error hashing key:", err.Error())
+		return
+	}
+	fmt.Printf("Hash for the \"hash\" of the key named '%s' in config.json:\n", params[0])
+	fmt.Println(hash)
+}
@@ -0,0 +1,224 @@
+package command
+
+import (
+	"ExeProcessManager/process"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (cli *CLI) createRule(params []string) {
+	params, flags := splitFlags(params)
+	if len(params) < 2 {
+		fmt.Println("Usage: createrule <rule_name> <time> [options]")
+		fmt.Println("Time:    Unix timestamp, RFC1123 time, \"every D\", \"D after previous run finishes\" or a cron expression")
+		fmt.Println("Options: --from=TIME --until=TIME --tz=ZONE --dst-gap=<next|skip> --dst-overlap=<once|twice>")
+		fmt.Println("         --misfire=<run_once|skip|run_all> --concurrency=<forbid|allow|replace|queue> --timeout=D")
+		fmt.Println("         --retries=N --retry-backoff=<fixed|exponential> --retry-delay=D --retry-max-delay=D")
+		return
+	}
+	// The time may span several words, e.g. a cron expression.
+	name, schedule := params[0], strings.Join(params[1:], " ")
+
+	opts, err := ruleOptions(flags)
+	if err != nil {
+		fmt.Println("Error:", err.Error())
+		return
+	}
+	if err := cli.manager.CreateTimingRule(name, schedule, opts...); err != nil {
+		fmt.Println("Error creating rule:", err.Error())
+		return
+	}
+	fmt.Printf("Timing rule '%s' created.\n", name)
+}
+
+// ruleOptions turns the options of the 'createrule' command into rule options.
+func ruleOptions(flags flagSet) ([]process.RuleOption, error) {
+	var opts []process.RuleOption
+
+	if flags.Has("from") || flags.Has("until") {
+		start, err := timeFlag(flags, "from")
+		if err != nil {
+			return nil, err
+		}
+		end, err := timeFlag(flags, "until")
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, process.WithWindow(start, end))
+	}
+	if zone, ok := flags.Get("tz"); ok {
+		opts = append(opts, process.WithTimeZone(zone))
+	}
+	if flags.Has("dst-gap") || flags.Has("dst-overlap") {
+		gap, _ := flags.Get("dst-gap")
+		overlap, _ := flags.Get("dst-overlap")
+		opts = append(opts, process.WithDSTPolicy(gap, overlap))
+	}
+	if policy, ok := flags.Get("misfire"); ok {
+		opts = append(opts, process.WithMisfirePolicy(policy))
+	}
+	if policy, ok := flags.Get("concurrency"); ok {
+		opts = append(opts, process.WithConcurrencyPolicy(policy))
+	}
+	if flags.Has("timeout") {
+		timeout, err := durationFlag(flags, "timeout")
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, process.WithTimeout(timeout))
+	}
+
+	if value, ok := flags.Get("retries"); ok {
+		retries, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for --retries: %w", err)
+		}
+		policy := process.RetryPolicy{MaxRetries: retries}
+		policy.Backoff, _ = flags.Get("retry-backoff")
+		durations := map[string]*process.Duration{
+			"retry-delay":     &policy.Delay,
+			"retry-max-delay": &policy.MaxDelay,
+		}
+		for key, target := range durations {
+			d, err := durationFlag(flags, key)
+			if err != nil {
+				return nil, err
+			}
+			*target = process.Duration(d)
+		}
+		opts = append(opts, process.WithRetryPolicy(policy))
+	}
+
+	return opts, nil
+}
+
+// timeFlag parses an RFC 3339 or RFC1123 time option, returning the zero
+// time if it is not given.
+func timeFlag(flags flagSet, key string) (time.Time, error) {
+	value, ok := flags.Get(key)
+	if !ok {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC1123, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid value for --%s, expected an RFC 3339 or RFC1123 time: %s", key, value)
+	}
+	return t, nil
+}
+
+func (cli *CLI) listRules() {
+	rules, err := cli.manager.TimingRules()
+	if err != nil {
+		fmt.Println("Error:", err.Error())
+		return
+	}
+	if len(rules) == 0 {
+		fmt.Println("No timing rules have been created.")
+		return
+	}
+
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	now := time.Now()
+	fmt.Println("--- Timing Rules ---")
+	for _, name := range names {
+		rule := rules[name]
+		next := "-"
+		if at, ok := rule.NextFire(now); ok {
+			next = at.Format(time.RFC1123)
+		} else if rule.IsFixedDelay() {
+			next = "after each run"
+		}
+		fmt.Printf("Name: %-15s | Next: %-29s | Rule: %s\n", name, next, rule)
+	}
+}
+
+func (cli *CLI) deleteRule(params []string) {
+	if len(params) < 1 {
+		fmt.Println("Usage: deleterule <rule_name>")
+		return
+	}
+	if err := cli.manager.DeleteTimingRule(params[0]); err != nil {
+		fmt.Println("Error deleting rule:", err.Error())
+		return
+	}
+	fmt.Printf("Timing rule '%s' deleted.\n", params[0])
+}
+
+func (cli *CLI) setJob(params []string) {
+	if len(params) < 2 {
+		fmt.Println("Usage: setjob <process_name> <rule_name>")
+		return
+	}
+	proc, err := cli.manager.GetProcessByName(params[0])
+	if err != nil {
+		fmt.Println("Error:", err.Error())
+		return
+	}
+	if err := proc.SetJob(params[1]); err != nil {
+		fmt.Println("Error setting job:", err.Error())
+		return
+	}
+	fmt.Printf("Timing rule '%s' set as the job of process '%s'.\n", params[1], proc.Name)
+}
+
+func (cli *CLI) unsetJob(params []string) {
+	if len(params) < 1 {
+		fmt.Println("Usage: unsetjob <process_name>")
+		return
+	}
+	proc, err := cli.manager.GetProcessByName(params[0])
+	if err != nil {
+		fmt.Println("Error:", err.Error())
+		return
+	}
+	if err := proc.UnsetJob(); err != nil {
+		fmt.Println("Error unsetting job:", err.Error())
+		return
+	}
+	fmt.Printf("Job of process '%s' unset.\n", proc.Name)
+}
+
+func (cli *CLI) startJob(params []string) {
+	if len(params) < 1 {
+		fmt.Println("Usage: startjob <process_name>")
+		return
+	}
+	proc, err := cli.manager.GetProcessByName(params[0])
+	if err != nil {
+		fmt.Println("Error:", err.Error())
+		return
+	}
+	if err := proc.StartJob(); err != nil {
+		fmt.Println("Error starting job:", err.Error())
+		return
+	}
+	fmt.Printf("Job of process '%s' started.\n", proc.Name)
+}
+
+func (cli *CLI) cancelJob(params []string) {
+	if len(params) < 1 {
+		fmt.Println("Usage: canceljob <process_name>")
+		return
+	}
+	proc, err := cli.manager.GetProcessByName(params[0])
+	if err != nil {
+		fmt.Println("Error:", err.Error())
+		return
+	}
+	if err := proc.CancelJob(); err != nil {
+		fmt.Println("Error cancelling job:", err.Error())
+		return
+	}
+	fmt.Printf("Job of process '%s' cancelled.\n", proc.Name)
+}
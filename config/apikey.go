@@ -0,0 +1,250 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Scopes of an API key. Each scope includes the ones before it.
+const (
+	ScopeRead    = "read"    // list and inspect processes, logs, runs, rules and the schedule
+	ScopeControl = "control" // also start, stop, restart and signal processes and jobs
+	ScopeAdmin   = "admin"   // also add, change and remove processes, jobs and rules
+)
+
+// scopeLevels orders the scopes from the least to the most powerful.
+var scopeLevels = []string{ScopeRead, ScopeControl, ScopeAdmin}
+
+// APIKey is a key accepted by the API together with what it may do. In the
+// configuration file a key can also be given as a plain string, which is an
+// unrestricted admin key.
+type APIKey struct {
+	Name      string     `json:"name"`           // shown in logs instead of the key
+	Key       string     `json:"key,omitempty"`  // the key itself; prefer Hash
+	Hash      string     `json:"hash,omitempty"` // salted hash of the key, see HashKey
+	Scopes    []string   `json:"scopes"`
+	Processes []string   `json:"processes,omitempty"` // names or label selectors such as "team=web"; all processes if empty
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// UnmarshalJSON accepts a key object or a plain key string.
+func (k *APIKey) UnmarshalJSON(data []byte) error {
+	var key string
+	if err := json.Unmarshal(data, &key); err == nil {
+		*k = APIKey{Key: key, Scopes: []string{ScopeAdmin}}
+		return nil
+	}
+	type plain APIKey // Without the UnmarshalJSON method
+	return json.Unmarshal(data, (*plain)(k))
+}
+
+// Validate checks that the key or its hash is set and that it has known
+// scopes.
+func (k APIKey) Validate() error {
+	if (k.Key == "") == (k.Hash == "") {
+		return fmt.Errorf("key '%s' must have either a key or a hash", k.Name)
+	}
+	if k.Hash != "" {
+		if err := k.validateHash(); err != nil {
+			return err
+		}
+	}
+	if len(k.Scopes) == 0 {
+		return fmt.Errorf("key '%s' has no scopes", k.Name)
+	}
+	for _, scope := range k.Scopes {
+		if !slices.Contains(scopeLevels, scope) {
+			return fmt.Errorf("invalid scope '%s': must be %s, %s or %s", scope, ScopeRead, ScopeControl, ScopeAdmin)
+		}
+	}
+	for _, entry := range k.Processes {
+		if entry == "" {
+			return fmt.Errorf("key '%s' has an empty process entry", k.Name)
+		}
+	}
+	return nil
+}
+
+// HasScope reports whether the key has the scope, or one that includes it.
+func (k APIKey) HasScope(scope string) bool {
+	needed := slices.Index(scopeLevels, scope)
+	for _, s := range k.Scopes {
+		if slices.Index(scopeLevels, s) >= needed {
+			return true
+		}
+	}
+	return false
+}
+
+// Restricted reports whether the key is limited to some processes.
+func (k APIKey) Restricted() bool {
+	return len(k.Processes) > 0
+}
+
+// Expired reports whether the key has expired at the given time.
+func (k APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// Minted keys are hashed with SHA-256 and a random salt. They carry 256
+// random bits, so a slow hash would not make them harder to guess and would
+// only slow down every request. Keys written into the configuration file may
+// be chosen by people, so their hashes are made with Argon2id instead.
+const (
+	hashScheme = "sha256"
+	saltSize   = 16
+)
+
+// Argon2id parameters of HashKey, the minimum recommended by OWASP. Hashes
+// carry their parameters, so changing these does not break existing ones.
+const (
+	argon2Scheme  = "argon2id"
+	argon2Time    = 2
+	argon2Memory  = 19 * 1024 // KiB
+	argon2Threads = 1
+	argon2KeyLen  = 32
+)
+
+// HashKey returns an Argon2id hash of a key in the PHC string format
+// ($argon2id$v=19$m=...,t=...,p=...$<salt>$<hash>), as given in the hash
+// of a key in the configuration file.
+func HashKey(key string) (string, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	sum := argon2.IDKey([]byte(key), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2Scheme, argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(sum)), nil
+}
+
+// keyNameSeparator ends the name at the start of a configured key. The name
+// is not secret: it picks the one Argon2id hash the key is checked against,
+// so no request costs more than one slow check.
+const keyNameSeparator = "."
+
+// NamedKey returns the key a client sends for the configured key with the
+// given name and secret, <name>.<secret>. The hash in the configuration file
+// is that of the whole of it.
+func NamedKey(name, secret string) (string, error) {
+	if err := validateKeyName(name); err != nil {
+		return "", err
+	}
+	if secret == "" {
+		return "", fmt.Errorf("the secret of key '%s' must not be empty", name)
+	}
+	return name + keyNameSeparator + secret, nil
+}
+
+// validateKeyName checks the name of a configured key with an Argon2id hash.
+func validateKeyName(name string) error {
+	if name == "" || strings.Contains(name, keyNameSeparator) {
+		return fmt.Errorf("invalid key name '%s': a key with an %s hash needs a name without '%s'", name, argon2Scheme, keyNameSeparator)
+	}
+	return nil
+}
+
+// argon2Hash is a parsed Argon2id hash.
+type argon2Hash struct {
+	time, memory uint32
+	threads      uint8
+	salt, sum    []byte
+}
+
+// parseArgon2Hash reads a hash made by HashKey.
+func parseArgon2Hash(hash string) (argon2Hash, error) {
+	var h argon2Hash
+	var version int
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != argon2Scheme {
+		return h, fmt.Errorf("invalid key hash: expected $%s$v=%d$m=<memory>,t=<time>,p=<threads>$<salt>$<hash>", argon2Scheme, argon2.Version)
+	}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return h, fmt.Errorf("invalid key hash: unsupported %s version '%s'", argon2Scheme, parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.time, &h.threads); err != nil || h.time == 0 || h.threads == 0 {
+		return h, fmt.Errorf("invalid key hash parameters '%s'", parts[3])
+	}
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return h, fmt.Errorf("invalid key hash salt: %w", err)
+	}
+	if h.sum, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.sum) == 0 {
+		return h, fmt.Errorf("invalid key hash sum")
+	}
+	return h, nil
+}
+
+// hashMintedKey returns a salted SHA-256 hash of a minted key.
+func hashMintedKey(key string) (string, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	sum := sha256.Sum256(append(salt, key...))
+	return hashScheme + "$" + hex.EncodeToString(salt) + "$" + hex.EncodeToString(sum[:]), nil
+}
+
+// parseHash splits a hash made by hashMintedKey into its salt and sum.
+func parseHash(hash string) (salt, sum []byte, err error) {
+	scheme, rest, _ := strings.Cut(hash, "$")
+	saltHex, sumHex, ok := strings.Cut(rest, "$")
+	if scheme != hashScheme || !ok {
+		return nil, nil, fmt.Errorf("invalid key hash: expected %s$<salt>$<sum>", hashScheme)
+	}
+	if salt, err = hex.DecodeString(saltHex); err != nil {
+		return nil, nil, fmt.Errorf("invalid key hash salt: %w", err)
+	}
+	if sum, err = hex.DecodeString(sumHex); err != nil || len(sum) != sha256.Size {
+		return nil, nil, fmt.Errorf("invalid key hash sum")
+	}
+	return salt, sum, nil
+}
+
+// slowHash reports whether the key is stored as an Argon2id hash, which
+// takes tens of milliseconds to check.
+func (k APIKey) slowHash() bool {
+	return strings.HasPrefix(k.Hash, "$"+argon2Scheme+"$")
+}
+
+// validateHash checks that the hash is one HashKey or hashMintedKey made.
+func (k APIKey) validateHash() error {
+	if k.slowHash() {
+		_, err := parseArgon2Hash(k.Hash)
+		return err
+	}
+	_, _, err := parseHash(k.Hash)
+	return err
+}
+
+// Matches reports whether key is the key of k. Sums are compared in
+// constant time.
+func (k APIKey) Matches(key string) bool {
+	switch {
+	case k.Hash == "":
+		return subtle.ConstantTimeCompare([]byte(k.Key), []byte(key)) == 1
+	case k.slowHash():
+		h, err := parseArgon2Hash(k.Hash)
+		if err != nil {
+			return false
+		}
+		actual := argon2.IDKey([]byte(key), h.salt, h.time, h.memory, h.threads, uint32(len(h.sum)))
+		return subtle.ConstantTimeCompare(actual, h.sum) == 1
+	default:
+		salt, sum, err := parseHash(k.Hash)
+		if err != nil {
+			return false
+		}
+		actual := sha256.Sum256(append(salt, key...))
+		return subtle.ConstantTimeCompare(actual[:], sum) == 1
+	}
+}
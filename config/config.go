@@ -2,7 +2,9 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"time"
 )
 
 // Config holds all configuration for the application.
@@ -11,7 +13,23 @@ type Config struct {
 	ScheduleDir      string   `json:"schedule_directory"`
 	LogLevel         string   `json:"log_level"`
 	ApiListenAddress string   `json:"api_listen_address"`
-	ApiKeys          []string `json:"api_keys"` // Added for security
+	ApiKeys          []APIKey `json:"api_keys"` // Added for security
+
+	// AllowPlaintextAPIKeys accepts keys given in plaintext in ApiKeys
+	// rather than by their hash.
+	AllowPlaintextAPIKeys bool `json:"allow_plaintext_api_keys"`
+
+	OutputLogs   OutputLogConfig `json:"output_logs"`
+	CgroupParent string          `json:"cgroup_parent"` // cgroup v2 directory holding per-process cgroups, /sys/fs/cgroup/exepm if empty
+}
+
+// OutputLogConfig controls how the captured stdout/stderr of managed
+// processes is rotated. Zero values fall back to the defaults noted below.
+type OutputLogConfig struct {
+	MaxSizeMB int    `json:"max_size_mb"` // rotate once the active file reaches this size (default 10)
+	MaxAge    string `json:"max_age"`     // rotate once the active file is older than this, e.g. "24h" (default never)
+	Compress  bool   `json:"compress"`    // gzip rotated files
+	Retention int    `json:"retention"`   // number of rotated files kept per process (default 5)
 }
 
 // Load reads a configuration file from the given path and returns a Config struct.
@@ -28,5 +46,38 @@ func Load(path string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	if _, err := cfg.OutputLogs.MaxAgeDuration(); err != nil {
+		return nil, err
+	}
+	for i, key := range cfg.ApiKeys {
+		if err := key.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid api_keys[%d]: %w", i, err)
+		}
+		if key.Key != "" && !cfg.AllowPlaintextAPIKeys {
+			return nil, fmt.Errorf("api_keys[%d] is in plaintext: give its hash from the hashkey command instead, or set allow_plaintext_api_keys", i)
+		}
+		// Configured keys may be chosen by people, so only a slow hash will do.
+		if key.Hash != "" && !key.slowHash() {
+			return nil, fmt.Errorf("invalid api_keys[%d]: the hash must be an %s hash from the hashkey command", i, argon2Scheme)
+		}
+		if key.slowHash() {
+			if err := validateKeyName(key.Name); err != nil {
+				return nil, fmt.Errorf("invalid api_keys[%d]: %w", i, err)
+			}
+		}
+	}
 	return cfg, nil
-}
\ No newline at end of file
+}
+
+// MaxAgeDuration parses MaxAge. An empty value yields zero, which disables
+// age-based rotation.
+func (c OutputLogConfig) MaxAgeDuration() (time.Duration, error) {
+	if c.MaxAge == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.MaxAge)
+	if err != nil {
+		return 0, fmt.Errorf("invalid output_logs.max_age '%s': %w", c.MaxAge, err)
+	}
+	return d, nil
+}
@@ -1,9 +1,13 @@
 package config
 
 import (
+	"crypto/sha256"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestLoad_Success tests the successful loading of a valid configuration file.
@@ -65,4 +69,175 @@ func TestLoad_InvalidJSON(t *testing.T) {
 	if err == nil {
 		t.Fatal("Load() should have returned an error for invalid JSON, but it didn't")
 	}
-}
\ No newline at end of file
+}
+
+// TestLoad_InvalidOutputLogAge tests that Load rejects a malformed rotation age.
+func TestLoad_InvalidOutputLogAge(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.json")
+
+	if err := os.WriteFile(configPath, []byte(`{"output_logs": {"max_age": "one day"}}`), 0644); err != nil {
+		t.Fatalf("failed to write temporary config file: %v", err)
+	}
+
+	_, err := Load(configPath)
+	if err == nil {
+		t.Fatal("Load() should have returned an error for an invalid max_age, but it didn't")
+	}
+}
+
+// TestLoad_APIKeys tests that keys load as plain strings or scoped objects.
+func TestLoad_APIKeys(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.json")
+
+	configContent := `{"allow_plaintext_api_keys": true, "api_keys": [
+		"legacy-key",
+		{"name": "monitoring", "key": "read-key", "scopes": ["read"]},
+		{"name": "deploy", "key": "deploy-key", "scopes": ["control"], "processes": ["web", "team=deploy"]}
+	]}`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write temporary config file: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() returned an unexpected error: %v", err)
+	}
+	if len(cfg.ApiKeys) != 3 {
+		t.Fatalf("expected 3 keys, got %d", len(cfg.ApiKeys))
+	}
+	legacy, monitoring, deploy := cfg.ApiKeys[0], cfg.ApiKeys[1], cfg.ApiKeys[2]
+	if legacy.Key != "legacy-key" || !legacy.HasScope(ScopeAdmin) || legacy.Restricted() {
+		t.Errorf("expected a plain key to be an unrestricted admin key, got %+v", legacy)
+	}
+	if !monitoring.HasScope(ScopeRead) || monitoring.HasScope(ScopeControl) {
+		t.Errorf("expected a read-only key, got %+v", monitoring)
+	}
+	if !deploy.HasScope(ScopeRead) || !deploy.HasScope(ScopeControl) || deploy.HasScope(ScopeAdmin) || !deploy.Restricted() {
+		t.Errorf("expected a restricted control key, got %+v", deploy)
+	}
+
+	if err := os.WriteFile(configPath, []byte(`{"api_keys": [{"name": "bad", "key": "k", "scopes": ["root"]}]}`), 0644); err != nil {
+		t.Fatalf("failed to write temporary config file: %v", err)
+	}
+	if _, err := Load(configPath); err == nil {
+		t.Fatal("Load() should have returned an error for an unknown scope, but it didn't")
+	}
+
+	// Keys are given by their Argon2id hash unless plaintext is allowed.
+	hash, err := HashKey("admin.admin-key")
+	if err != nil {
+		t.Fatalf("HashKey() returned an unexpected error: %v", err)
+	}
+	fast, err := hashMintedKey("admin.admin-key")
+	if err != nil {
+		t.Fatalf("hashMintedKey() returned an unexpected error: %v", err)
+	}
+	tests := []struct {
+		content string
+		valid   bool
+	}{
+		{`{"api_keys": [{"name": "admin", "hash": "` + hash + `", "scopes": ["admin"]}]}`, true},
+		{`{"api_keys": ["legacy-key"]}`, false},
+		{`{"api_keys": [{"name": "admin", "key": "admin-key", "scopes": ["admin"]}]}`, false},
+		{`{"api_keys": [{"name": "admin", "hash": "` + fast + `", "scopes": ["admin"]}]}`, false},
+		{`{"api_keys": [{"hash": "` + hash + `", "scopes": ["admin"]}]}`, false},
+		{`{"api_keys": [{"name": "ad.min", "hash": "` + hash + `", "scopes": ["admin"]}]}`, false},
+	}
+	for _, tt := range tests {
+		if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+			t.Fatalf("failed to write temporary config file: %v", err)
+		}
+		if _, err := Load(configPath); (err == nil) != tt.valid {
+			t.Errorf("Load(%s): expected valid=%v, got error %v", tt.content, tt.valid, err)
+		}
+	}
+}
+
+// TestKeyStore tests minting, authenticating and revoking keys, and that
+// only hashes reach the disk.
+func TestKeyStore(t *testing.T) {
+	hash, err := HashKey("hashed.hashed-key")
+	if err != nil {
+		t.Fatalf("HashKey() returned an unexpected error: %v", err)
+	}
+	cfg := &Config{DataDir: t.TempDir(), ApiKeys: []APIKey{
+		{Key: "config-key", Scopes: []string{ScopeAdmin}},
+		{Name: "hashed", Hash: hash, Scopes: []string{ScopeRead}},
+	}}
+	store, err := NewKeyStore(cfg)
+	if err != nil {
+		t.Fatalf("NewKeyStore() returned an unexpected error: %v", err)
+	}
+	if key := store.Authenticate("config-key"); key == nil || key.Name != "config-1" {
+		t.Fatalf("expected the configured key to authenticate, got %+v", key)
+	}
+	for range 2 { // The second time from the cache of verified keys
+		if key := store.Authenticate("hashed.hashed-key"); key == nil || key.Name != "hashed" {
+			t.Fatalf("expected the key with an Argon2id hash to authenticate, got %+v", key)
+		}
+	}
+	if store.Authenticate("hashed-key") != nil || store.Authenticate("other.hashed-key") != nil {
+		t.Fatal("expected a key without the name of its hash to be rejected")
+	}
+	for range 2 { // The second time from the cache of rejected keys
+		if store.Authenticate("hashed.wrong-key") != nil {
+			t.Fatal("expected a wrong key to be rejected")
+		}
+	}
+	if _, ok := store.rejected.Load(sha256.Sum256([]byte("hashed.wrong-key"))); !ok || store.rejects.Load() != 1 {
+		t.Errorf("expected the wrong key to be checked once and remembered, %d checks failed", store.rejects.Load())
+	}
+
+	expiry := time.Now().Add(time.Hour)
+	secret, minted, err := store.Mint("monitoring", []string{ScopeRead}, nil, &expiry)
+	if err != nil {
+		t.Fatalf("Mint() returned an unexpected error: %v", err)
+	}
+	if minted.Key != "" || minted.Hash != "" {
+		t.Errorf("expected the minted key to be returned without secrets, got %+v", minted)
+	}
+	if _, _, err := store.Mint("monitoring", []string{ScopeRead}, nil, nil); !errors.Is(err, ErrKeyExists) {
+		t.Errorf("expected a duplicate name to be rejected, got %v", err)
+	}
+	if _, _, err := store.Mint("bad", []string{"root"}, nil, nil); !errors.Is(err, ErrKeyInvalid) {
+		t.Errorf("expected an unknown scope to be rejected, got %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(cfg.DataDir, "api_keys.json"))
+	if err != nil {
+		t.Fatalf("failed to read the key file: %v", err)
+	}
+	if strings.Contains(string(data), secret) || strings.Contains(string(data), "config-key") {
+		t.Fatalf("expected only hashes on disk, got %s", data)
+	}
+	for _, key := range store.List() {
+		if key.Key != "" || key.Hash != "" {
+			t.Errorf("expected keys to be listed without secrets, got %+v", key)
+		}
+	}
+
+	// A new store, as after a restart, reads the minted key back.
+	store, err = NewKeyStore(cfg)
+	if err != nil {
+		t.Fatalf("NewKeyStore() returned an unexpected error: %v", err)
+	}
+	key := store.Authenticate(secret)
+	if key == nil || key.Name != "monitoring" || key.Expired(time.Now()) || !key.Expired(expiry) {
+		t.Fatalf("expected the minted key to authenticate until it expires, got %+v", key)
+	}
+
+	if err := store.Revoke("monitoring"); err != nil {
+		t.Fatalf("Revoke() returned an unexpected error: %v", err)
+	}
+	if store.Authenticate(secret) != nil {
+		t.Error("expected a revoked key to be rejected")
+	}
+	if err := store.Revoke("monitoring"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected an unknown key to be reported, got %v", err)
+	}
+	if err := store.Revoke("config-1"); !errors.Is(err, ErrKeyReadOnly) {
+		t.Errorf("expected a configured key not to be revocable, got %v", err)
+	}
+}
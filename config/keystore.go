@@ -0,0 +1,255 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Errors returned by KeyStore, which callers tell apart with errors.Is.
+var (
+	ErrKeyNotFound = errors.New("api key not found")
+	ErrKeyExists   = errors.New("api key already exists")
+	ErrKeyReadOnly = errors.New("api key is defined in the configuration file")
+	ErrKeyInvalid  = errors.New("invalid api key")
+)
+
+// keyPrefix starts every minted key, so leaked keys are easy to recognize.
+const keyPrefix = "epm_"
+
+// KeyStore holds the API keys: those of the configuration file and those
+// minted while the daemon runs, which are saved with their hashes only in
+// <data_directory>/api_keys.json. It is safe for concurrent use.
+type KeyStore struct {
+	mu     sync.RWMutex
+	path   string
+	static []APIKey // from the configuration file, cannot be revoked
+	minted []APIKey
+
+	// Argon2id hashes are slow and memory hungry to check, so keys that
+	// were checked against one are remembered by their SHA-256 sum, and only
+	// a few checks run at a time.
+	verified sync.Map // [sha256.Size]byte -> APIKey
+	rejected sync.Map // [sha256.Size]byte -> struct{}
+	rejects  atomic.Int64
+	slow     chan struct{}
+}
+
+// maxRejected bounds the number of rejected keys remembered. Once reached,
+// they are all forgotten.
+const maxRejected = 4096
+
+// NewKeyStore loads the minted keys and hashes the keys of the
+// configuration file, so no key is kept in memory in plaintext.
+func NewKeyStore(cfg *Config) (*KeyStore, error) {
+	s := &KeyStore{path: filepath.Join(cfg.DataDir, "api_keys.json"), slow: make(chan struct{}, runtime.NumCPU())}
+
+	for i, key := range cfg.ApiKeys {
+		if key.Name == "" {
+			key.Name = fmt.Sprintf("config-%d", i+1)
+		}
+		if key.Key != "" {
+			// The key is in the file in plaintext anyway, so a fast hash will do.
+			hash, err := hashMintedKey(key.Key)
+			if err != nil {
+				return nil, err
+			}
+			key.Key, key.Hash = "", hash
+		}
+		s.static = append(s.static, key)
+	}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read api keys: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.minted); err != nil {
+		return nil, fmt.Errorf("failed to decode api keys from %s: %w", s.path, err)
+	}
+	for _, key := range s.minted {
+		if err := key.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid api key in %s: %w", s.path, err)
+		}
+		if key.Hash == "" || key.slowHash() {
+			return nil, fmt.Errorf("invalid api key in %s: key '%s' must have a %s hash", s.path, key.Name, hashScheme)
+		}
+	}
+	return s, nil
+}
+
+// GenerateKey returns a new random key with 256 bits of entropy.
+func GenerateKey() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// Authenticate returns the key matching the given one, or nil. Expired keys
+// are returned too; the caller decides what to do with them.
+func (s *KeyStore) Authenticate(key string) *APIKey {
+	s.mu.RLock()
+	// Every key with a fast hash is compared so the time taken does not tell
+	// which matched.
+	var found, named *APIKey
+	for _, k := range slices.Concat(s.static, s.minted) {
+		if !k.slowHash() && k.Matches(key) && found == nil {
+			found = &k
+		}
+	}
+	// A configured key with an Argon2id hash is only checked if the key
+	// starts with its name.
+	if name, _, ok := strings.Cut(key, keyNameSeparator); ok && found == nil {
+		if i := slices.IndexFunc(s.static, func(k APIKey) bool { return k.slowHash() && k.Name == name }); i >= 0 {
+			k := s.static[i]
+			named = &k
+		}
+	}
+	s.mu.RUnlock()
+
+	if found != nil || named == nil {
+		return found
+	}
+	return s.authenticateSlow(key, *named)
+}
+
+// authenticateSlow checks key against k, a configured key with an Argon2id
+// hash.
+func (s *KeyStore) authenticateSlow(key string, k APIKey) *APIKey {
+	sum := sha256.Sum256([]byte(key))
+	if found, ok := s.verified.Load(sum); ok {
+		k := found.(APIKey)
+		return &k
+	}
+	if _, ok := s.rejected.Load(sum); ok {
+		return nil
+	}
+
+	s.slow <- struct{}{}
+	defer func() { <-s.slow }()
+	if !k.Matches(key) {
+		if s.rejects.Add(1) > maxRejected {
+			s.rejected.Clear()
+			s.rejects.Store(1)
+		}
+		s.rejected.Store(sum, struct{}{})
+		return nil
+	}
+	s.verified.Store(sum, k)
+	return &k
+}
+
+// Mint creates a key with the given name and permissions, saves its hash
+// and returns the key itself, which cannot be recovered later.
+func (s *KeyStore) Mint(name string, scopes, processes []string, expiresAt *time.Time) (string, APIKey, error) {
+	if name == "" {
+		return "", APIKey{}, fmt.Errorf("%w: the name must not be empty", ErrKeyInvalid)
+	}
+	key, err := GenerateKey()
+	if err != nil {
+		return "", APIKey{}, err
+	}
+	hash, err := hashMintedKey(key)
+	if err != nil {
+		return "", APIKey{}, err
+	}
+	minted := APIKey{Name: name, Hash: hash, Scopes: scopes, Processes: processes, ExpiresAt: expiresAt}
+	if err := minted.Validate(); err != nil {
+		return "", APIKey{}, fmt.Errorf("%w: %w", ErrKeyInvalid, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.find(name) != nil {
+		return "", APIKey{}, fmt.Errorf("%w: '%s'", ErrKeyExists, name)
+	}
+	if err := s.save(append(slices.Clone(s.minted), minted)); err != nil {
+		return "", APIKey{}, err
+	}
+	s.minted = append(s.minted, minted)
+	return key, minted.withoutSecret(), nil
+}
+
+// List returns all keys without their secrets or hashes, configured keys
+// first.
+func (s *KeyStore) List() []APIKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]APIKey, 0, len(s.static)+len(s.minted))
+	for _, key := range slices.Concat(s.static, s.minted) {
+		keys = append(keys, key.withoutSecret())
+	}
+	return keys
+}
+
+// Revoke deletes a minted key. Requests made with it fail from then on.
+func (s *KeyStore) Revoke(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.minted, func(k APIKey) bool { return k.Name == name })
+	if i < 0 {
+		if s.find(name) != nil {
+			return fmt.Errorf("%w: '%s', remove it there", ErrKeyReadOnly, name)
+		}
+		return fmt.Errorf("%w: '%s'", ErrKeyNotFound, name)
+	}
+	minted := slices.Delete(slices.Clone(s.minted), i, i+1)
+	if err := s.save(minted); err != nil {
+		return err
+	}
+	s.minted = minted
+	return nil
+}
+
+// find returns the key with the given name. The caller must hold the lock.
+func (s *KeyStore) find(name string) *APIKey {
+	for _, keys := range [][]APIKey{s.static, s.minted} {
+		if i := slices.IndexFunc(keys, func(k APIKey) bool { return k.Name == name }); i >= 0 {
+			return &keys[i]
+		}
+	}
+	return nil
+}
+
+// save writes the minted keys, replacing the file in one step so a crash
+// cannot leave it half written. The caller must hold the lock.
+func (s *KeyStore) save(minted []APIKey) error {
+	data, err := json.MarshalIndent(minted, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode api keys: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for api keys: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to save api keys: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to save api keys: %w", err)
+	}
+	return nil
+}
+
+// withoutSecret returns a copy of the key that can be shown.
+func (k APIKey) withoutSecret() APIKey {
+	k.Key, k.Hash = "", ""
+	return k
+}
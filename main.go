@@ -44,16 +44,30 @@ func main() {
 	}()
 
 	// 4. Initialize Core Components with Dependencies
-	// Note: We need a mechanism to load existing processes from disk on startup.
-	// This is a placeholder for that logic.
 	processManager := process.NewProcessManager(logger, cfg)
 	if err := processManager.LoadProcessesFromDisk(); err != nil {
 		logger.Error("failed to load existing processes", "error", err)
 		// Decide if you want to exit or continue with an empty manager
 	}
 
+	// Re-arm the jobs that were active when the daemon last stopped
+	scheduler := process.NewScheduler(processManager)
+	scheduler.Start()
+
+	// API keys of the configuration file and those minted at runtime
+	keys, err := config.NewKeyStore(cfg)
+	if err != nil {
+		logger.Error("failed to load API keys", "error", err)
+		os.Exit(1)
+	}
+	for _, key := range cfg.ApiKeys {
+		if key.Key != "" {
+			logger.Warn("an API key is stored in plaintext in the configuration, replace it with its hash from the hashkey command", "key", key.Name)
+		}
+	}
+
 	// 5. Start API Server in a Goroutine
-	processAPI := api.NewProcessAPI(processManager, logger, cfg)
+	processAPI := api.NewProcessAPI(processManager, logger, cfg, keys)
 	server := &http.Server{
 		Addr:    cfg.ApiListenAddress,
 		Handler: processAPI.Routes(),
@@ -67,7 +81,7 @@ func main() {
 	}()
 
 	// 6. Start the Command Line Interface (CLI)
-	cli := command.NewCLI(processManager, logger)
+	cli := command.NewCLI(processManager, keys, logger)
 	go cli.Start(ctx)
 
 	// 7. Wait for context to be cancelled (shutdown signal)
@@ -86,6 +100,9 @@ func main() {
 		logger.Info("API server stopped")
 	}
 
+	scheduler.Stop()
+	logger.Info("scheduler stopped")
+
 	// Add any other cleanup logic here (e.g., ensuring all processes are saved)
 	logger.Info("ExeProcessManager has been shut down. Goodbye!")
 }
@@ -0,0 +1,22 @@
+package process
+
+import "time"
+
+// Clock is the time source of the scheduler. Tests replace it to control
+// when jobs fire.
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a pending call created by Clock.AfterFunc.
+type Timer interface {
+	Stop() bool
+}
+
+// realClock is the wall clock used outside of tests.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer { return time.AfterFunc(d, f) }
@@ -0,0 +1,157 @@
+package process
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// What a recurring job does when an occurrence is due while the previous
+// run is still going.
+const (
+	ConcurrencyForbid  = "forbid"  // skip the occurrence (default)
+	ConcurrencyAllow   = "allow"   // start another instance next to it
+	ConcurrencyReplace = "replace" // stop the running instance and start a new one
+	ConcurrencyQueue   = "queue"   // run the occurrence right after the current run exits
+)
+
+// ValidateConcurrencyPolicy checks a concurrency policy name.
+func ValidateConcurrencyPolicy(policy string) error {
+	switch policy {
+	case "", ConcurrencyForbid, ConcurrencyAllow, ConcurrencyReplace, ConcurrencyQueue:
+		return nil
+	}
+	return fmt.Errorf("invalid concurrency policy '%s': must be %s, %s, %s or %s", policy, ConcurrencyForbid, ConcurrencyAllow, ConcurrencyReplace, ConcurrencyQueue)
+}
+
+// instance is an extra run started by the allow policy. It shares the
+// process's configuration and output log but is not its tracked run: it
+// does not show up in Pid or Stat and is never restarted.
+type instance struct {
+	cmd      *exec.Cmd
+	runID    string
+	timedOut bool          // stopped for exceeding the run timeout
+	stopping bool          // stopped on request
+	timer    Timer         // pending run timeout, if any
+	done     chan struct{} // closed once the instance has exited
+}
+
+// overlap applies the job's concurrency policy to an occurrence that is
+// due while the previous one is still going: its run is in progress or a
+// retry of it is pending. It reports whether the new occurrence should
+// start as the tracked run. The caller must hold the manager lock.
+func (p *Process) overlap() bool {
+	running, runID := p.Pid, p.RunID
+	reason := "the previous run is still going"
+	if p.Stat != StatRunning {
+		running, runID = 0, ""
+		reason = "a retry of the previous occurrence is pending"
+	}
+
+	switch p.Timing.Concurrency {
+	case ConcurrencyAllow:
+		if p.Stat != StatRunning {
+			return true // The retry starts next to it when it is due
+		}
+		pid, err := p.startInstance(newRunID(p.manager.clock.Now()), 1)
+		if err != nil {
+			p.manager.logger.Error("failed to start additional job instance", "name", p.Name, "error", err)
+			p.recordHistory(HistoryEntry{Event: EventSkipped, Reason: fmt.Sprintf("an additional instance failed to start: %v", err), Pid: running, RunID: runID})
+			return false
+		}
+		p.manager.logger.Info("started additional job instance", "name", p.Name, "pid", pid, "running_pid", running)
+		p.recordHistory(HistoryEntry{Event: EventAllowed, Reason: fmt.Sprintf("started next to run %d", running), Pid: pid})
+		return false
+
+	case ConcurrencyReplace:
+		p.manager.logger.Info("replacing running job instance", "name", p.Name, "pid", running)
+		p.recordHistory(HistoryEntry{Event: EventReplaced, Reason: reason, Pid: running, RunID: runID})
+		p.dropRetry("replaced by a new occurrence")
+		if p.Stat != StatRunning {
+			return true
+		}
+		// Stopping waits for the exit, which needs the lock held by our caller.
+		go p.replace()
+		return false
+
+	case ConcurrencyQueue:
+		if p.jobBacklog > 0 {
+			p.manager.logger.Warn("skipping job occurrence, another one is already queued", "name", p.Name)
+			p.recordHistory(HistoryEntry{Event: EventSkipped, Reason: "an occurrence is already queued", Pid: running, RunID: runID})
+			return false
+		}
+		p.jobBacklog++
+		p.manager.logger.Info("queued job occurrence until the previous one is done", "name", p.Name, "pid", running)
+		p.recordHistory(HistoryEntry{Event: EventQueued, Reason: reason, Pid: running, RunID: runID})
+		return false
+
+	default:
+		p.manager.logger.Warn("skipping job occurrence, the previous one is still going", "name", p.Name)
+		p.recordHistory(HistoryEntry{Event: EventSkipped, Reason: reason, Pid: running, RunID: runID})
+		return false
+	}
+}
+
+// replace stops the current run and starts a new occurrence in its place.
+func (p *Process) replace() {
+	if err := p.Stop(); err != nil {
+		p.manager.logger.Error("failed to stop job run for replacement", "name", p.Name, "error", err)
+	}
+
+	p.manager.processMutex.Lock()
+	defer p.manager.processMutex.Unlock()
+	if p.IsJobDeleted == 1 || p.Stat == StatRunning {
+		return
+	}
+	// The stopped run may have failed and asked for a retry of its own.
+	p.dropRetry("replaced by a new occurrence")
+	if err := p.startOccurrence(); err != nil {
+		p.manager.logger.Error("failed to start replacement job run", "name", p.Name, "error", err)
+	}
+}
+
+// startInstance starts an attempt of an occurrence as an extra instance
+// next to the tracked run. The caller must hold the manager lock.
+func (p *Process) startInstance(occurrence string, attempt int) (int, error) {
+	cmd, err := p.spawn(p.effectiveArgs(nil, ""))
+	if err != nil {
+		return 0, err
+	}
+	if p.instances == nil {
+		p.instances = map[int]*instance{}
+	}
+	start := p.manager.clock.Now()
+	inst := &instance{cmd: cmd, runID: newRunID(start), done: make(chan struct{})}
+	pid := cmd.Process.Pid
+	p.instances[pid] = inst
+	p.recordRunStart(HistoryEntry{Time: start, RunID: inst.runID, Trigger: TriggerSchedule, Pid: pid, Occurrence: occurrence, Attempt: attempt})
+	go p.watchInstance(pid, inst)
+	// The tracked run may be of another occurrence, or not a job run at all.
+	if timeout := p.jobStartOptions(occurrence, attempt).Timeout; timeout > 0 {
+		p.armInstanceTimeout(pid, inst, timeout)
+	}
+	return pid, nil
+}
+
+// watchInstance reaps an extra instance and forgets it once it has exited.
+func (p *Process) watchInstance(pid int, inst *instance) {
+	defer close(inst.done)
+	_ = inst.cmd.Wait()
+
+	code, sig := exitDetails(inst.cmd.ProcessState)
+	p.manager.processMutex.Lock()
+	defer p.manager.processMutex.Unlock()
+	delete(p.instances, pid)
+	if inst.timer != nil {
+		inst.timer.Stop()
+		inst.timer = nil
+	}
+	reason := ""
+	switch {
+	case inst.timedOut:
+		reason = ExitReasonTimedOut
+	case inst.stopping:
+		reason = ExitReasonStopped
+	}
+	p.recordRunEnd(inst.runID, pid, p.manager.clock.Now(), code, sig, reason)
+	p.manager.logger.Info("additional job instance exited", "name", p.Name, "pid", pid, "exit_code", code, "signal", sig)
+}
@@ -0,0 +1,94 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// lookupUser resolves a user given by name or numeric uid.
+func lookupUser(name string) (*user.User, error) {
+	u, err := user.Lookup(name)
+	if err == nil {
+		return u, nil
+	}
+	if _, convErr := strconv.Atoi(name); convErr == nil {
+		if u, idErr := user.LookupId(name); idErr == nil {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown user '%s': %w", name, err)
+}
+
+// lookupGroupID resolves a group given by name or numeric gid.
+func lookupGroupID(name string) (uint32, error) {
+	if g, err := user.LookupGroup(name); err == nil {
+		return parseID(g.Gid)
+	}
+	if gid, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return uint32(gid), nil
+	}
+	return 0, fmt.Errorf("unknown group '%s'", name)
+}
+
+// parseID converts a numeric uid or gid string from os/user.
+func parseID(id string) (uint32, error) {
+	value, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid numeric id '%s'", id)
+	}
+	return uint32(value), nil
+}
+
+// credential resolves User, Group and SupplementaryGroups into the
+// credential the child runs with, or nil if none of them is set. Without an
+// explicit Group the user's primary group is used, and without explicit
+// supplementary groups the user's own group list is used, so a child never
+// keeps the manager's groups.
+func (p *Process) credential() (*syscall.Credential, error) {
+	if p.User == "" && p.Group == "" && len(p.SupplementaryGroups) == 0 {
+		return nil, nil
+	}
+
+	cred := &syscall.Credential{Uid: uint32(os.Getuid()), Gid: uint32(os.Getgid())}
+	var u *user.User
+	if p.User != "" {
+		var err error
+		if u, err = lookupUser(p.User); err != nil {
+			return nil, err
+		}
+		if cred.Uid, err = parseID(u.Uid); err != nil {
+			return nil, err
+		}
+		if cred.Gid, err = parseID(u.Gid); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.Group != "" {
+		gid, err := lookupGroupID(p.Group)
+		if err != nil {
+			return nil, err
+		}
+		cred.Gid = gid
+	}
+
+	groups := p.SupplementaryGroups
+	if len(groups) == 0 && u != nil {
+		ids, err := u.GroupIds()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list groups of user '%s': %w", p.User, err)
+		}
+		groups = ids
+	}
+	for _, group := range groups {
+		gid, err := lookupGroupID(group)
+		if err != nil {
+			return nil, err
+		}
+		cred.Groups = append(cred.Groups, gid)
+	}
+	return cred, nil
+}
@@ -0,0 +1,295 @@
+package process
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronDescriptors maps the supported @-descriptors to their 5-field form.
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+var weekdayNames = map[string]int{
+	"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+}
+
+// cronSearchYears bounds the search for the next match, so impossible
+// expressions such as "0 0 30 2 *" terminate.
+const cronSearchYears = 5
+
+// cronSchedule is a parsed cron expression. Each field is a bit set of the
+// values it matches.
+type cronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+	domStar, dowStar                      bool // the field was '*' or '?'
+}
+
+// parseCron parses a standard 5-field expression (minute hour day-of-month
+// month day-of-week), a 6-field expression with a leading seconds field, or
+// one of the @-descriptors such as @hourly and @daily.
+func parseCron(expr string) (*cronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "@") {
+		fiveField, ok := cronDescriptors[strings.ToLower(expr)]
+		if !ok {
+			return nil, fmt.Errorf("unknown cron descriptor '%s'", expr)
+		}
+		expr = fiveField
+	}
+
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron expression '%s' must have 5 or 6 fields, got %d", expr, len(fields))
+	}
+
+	c := &cronSchedule{}
+	var err error
+	if c.second, _, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid seconds field: %w", err)
+	}
+	if c.minute, _, err = parseCronField(fields[1], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid minutes field: %w", err)
+	}
+	if c.hour, _, err = parseCronField(fields[2], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid hours field: %w", err)
+	}
+	if c.dom, c.domStar, err = parseCronField(fields[3], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("invalid day-of-month field: %w", err)
+	}
+	if c.month, _, err = parseCronField(fields[4], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("invalid month field: %w", err)
+	}
+	if c.dow, c.dowStar, err = parseCronField(fields[5], 0, 7, weekdayNames); err != nil {
+		return nil, fmt.Errorf("invalid day-of-week field: %w", err)
+	}
+	// Both 0 and 7 mean Sunday.
+	if c.dow&(1<<7) != 0 {
+		c.dow = c.dow&^(1<<7) | 1
+	}
+	return c, nil
+}
+
+// parseCronField parses a comma-separated list of '*', values, ranges
+// (a-b) and steps (*/n, a-b/n, a/n) into a bit set. It also reports
+// whether the field was a bare '*' or '?'.
+func parseCronField(field string, min, max int, names map[string]int) (uint64, bool, error) {
+	if field == "*" || field == "?" {
+		return rangeBits(min, max, 1), true, nil
+	}
+
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, false, fmt.Errorf("invalid step '%s'", stepPart)
+			}
+		}
+
+		var low, high int
+		switch {
+		case rangePart == "*" || rangePart == "?":
+			low, high = min, max
+		case strings.Contains(rangePart, "-"):
+			lowPart, highPart, _ := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = cronValue(lowPart, names); err != nil {
+				return 0, false, err
+			}
+			if high, err = cronValue(highPart, names); err != nil {
+				return 0, false, err
+			}
+		default:
+			var err error
+			if low, err = cronValue(rangePart, names); err != nil {
+				return 0, false, err
+			}
+			high = low
+			if hasStep {
+				high = max // "a/n" means from a to the end in steps of n
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return 0, false, fmt.Errorf("'%s' is outside %d-%d", part, min, max)
+		}
+		bits |= rangeBits(low, high, step)
+	}
+	return bits, false, nil
+}
+
+// cronValue parses a number or, if names is given, a name such as "MON".
+func cronValue(value string, names map[string]int) (int, error) {
+	if n, ok := names[strings.ToUpper(value)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value '%s'", value)
+	}
+	return n, nil
+}
+
+// rangeBits returns a bit set with every step-th bit from low to high.
+func rangeBits(low, high, step int) uint64 {
+	var bits uint64
+	for i := low; i <= high; i += step {
+		bits |= 1 << uint(i)
+	}
+	return bits
+}
+
+// dayMatches applies the cron rule for the two day fields: when both are
+// restricted a day matches if either does, otherwise both must match.
+func (c *cronSchedule) dayMatches(date time.Time) bool {
+	domMatch := c.dom&(1<<uint(date.Day())) != 0
+	dowMatch := c.dow&(1<<uint(date.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// nextWall returns the first wall-clock time strictly after wall that
+// matches the schedule. Wall-clock times are represented in UTC, which has
+// no DST, so the search is purely calendar based; the caller maps the
+// result to a real instant. The zero time means there is no match.
+func (c *cronSchedule) nextWall(wall time.Time) time.Time {
+	wall = wall.Truncate(time.Second).Add(time.Second)
+	date := time.Date(wall.Year(), wall.Month(), wall.Day(), 0, 0, 0, 0, time.UTC)
+	limit := date.AddDate(cronSearchYears, 0, 0)
+
+	for first := true; date.Before(limit); date, first = date.AddDate(0, 0, 1), false {
+		if c.month&(1<<uint(date.Month())) == 0 || !c.dayMatches(date) {
+			continue
+		}
+		for h := 0; h < 24; h++ {
+			if c.hour&(1<<uint(h)) == 0 || (first && h < wall.Hour()) {
+				continue
+			}
+			sameHour := first && h == wall.Hour()
+			for m := 0; m < 60; m++ {
+				if c.minute&(1<<uint(m)) == 0 || (sameHour && m < wall.Minute()) {
+					continue
+				}
+				sameMinute := sameHour && m == wall.Minute()
+				for s := 0; s < 60; s++ {
+					if c.second&(1<<uint(s)) == 0 || (sameMinute && s < wall.Second()) {
+						continue
+					}
+					return time.Date(date.Year(), date.Month(), date.Day(), h, m, s, 0, time.UTC)
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// wallClock returns the calendar fields of t in loc as a UTC time.
+func wallClock(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// next returns the first instant strictly after t that matches the
+// schedule in loc, or false if there is none. Wall-clock times skipped by a
+// DST gap either run when the gap ends or are skipped (skipGap); times
+// repeated by a DST overlap run on their first occurrence only or on both
+// (runTwice).
+func (c *cronSchedule) next(t time.Time, loc *time.Location, skipGap, runTwice bool) (time.Time, bool) {
+	wall := wallClock(t, loc)
+	// Clocks going back soon repeat wall-clock times that are earlier than
+	// t's, so the search starts from where they will be set back to.
+	if _, end := t.In(loc).ZoneBounds(); !end.IsZero() {
+		if back := wallClock(end, loc); back.Before(wall) {
+			wall = back.Add(-time.Nanosecond)
+		}
+	}
+
+	for {
+		wall = c.nextWall(wall)
+		if wall.IsZero() {
+			return time.Time{}, false
+		}
+
+		instants := resolveWall(wall, loc)
+		switch {
+		case len(instants) == 0 && skipGap:
+			continue
+		case len(instants) == 0:
+			instants = []time.Time{gapEnd(wall, loc)}
+		case !runTwice:
+			instants = instants[:1]
+		}
+		for _, instant := range instants {
+			if instant.After(t) {
+				return instant, true
+			}
+		}
+	}
+}
+
+// resolveWall returns the instants, in order, at which the clock in loc
+// shows wall: none in a DST gap, two in a DST overlap and one otherwise.
+func resolveWall(wall time.Time, loc *time.Location) []time.Time {
+	guess := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, loc)
+
+	// The candidates are wall read with the offset of the zone period guess
+	// falls in and of the periods on either side of it.
+	_, offset := guess.Zone()
+	offsets := []int{offset}
+	start, end := guess.ZoneBounds()
+	if !start.IsZero() {
+		_, before := start.Add(-time.Nanosecond).Zone()
+		offsets = append(offsets, before)
+	}
+	if !end.IsZero() {
+		_, after := end.Zone()
+		offsets = append(offsets, after)
+	}
+
+	var instants []time.Time
+	for _, offset := range offsets {
+		instant := time.Unix(wall.Unix()-int64(offset), 0).In(loc)
+		if !wallClock(instant, loc).Equal(wall) || slices.ContainsFunc(instants, instant.Equal) {
+			continue
+		}
+		instants = append(instants, instant)
+	}
+	slices.SortFunc(instants, func(a, b time.Time) int { return a.Compare(b) })
+	return instants
+}
+
+// gapEnd returns the instant a DST gap containing wall ends, which is the
+// first valid time after it.
+func gapEnd(wall time.Time, loc *time.Location) time.Time {
+	// time.Date normalizes a missing time to one side of the gap; the
+	// transition is the boundary of that side's zone period facing wall.
+	guess := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, loc)
+	start, end := guess.ZoneBounds()
+	if wallClock(guess, loc).Before(wall) {
+		return end
+	}
+	return start
+}
@@ -0,0 +1,39 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of errors returned by the manager and its processes. The errors
+// carry a detailed message; callers tell the kinds apart with errors.Is.
+var (
+	ErrNotFound       = errors.New("not found")             // no process or timing rule has the name
+	ErrAlreadyExists  = errors.New("already exists")        // a process or timing rule already has the name
+	ErrAlreadyRunning = errors.New("already running")       // the process, or its job, is already running
+	ErrNotRunning     = errors.New("not running")           // the process, or its job, is not running
+	ErrScheduledOnly  = errors.New("scheduled only")        // the process only runs from its job
+	ErrNotScheduled   = errors.New("not scheduled")         // the process has no job, or its job has nothing left to run
+	ErrInUse          = errors.New("in use")                // a job uses the process or timing rule
+	ErrInvalidSpec    = errors.New("invalid specification") // a configuration value or option is not valid
+)
+
+// kindError is an error of one of the kinds above. Its message is the
+// detailed one; the kind only shows through errors.Is.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string   { return e.err.Error() }
+func (e *kindError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// errorf formats an error as fmt.Errorf does and gives it a kind.
+func errorf(kind error, format string, args ...any) error {
+	return withKind(kind, fmt.Errorf(format, args...))
+}
+
+// withKind gives err a kind, keeping its message.
+func withKind(kind, err error) error {
+	return &kindError{kind: kind, err: err}
+}
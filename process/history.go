@@ -0,0 +1,240 @@
+package process
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// History events.
+const (
+	EventRunStarted  = "run_started"  // a run of the process started
+	EventRunFinished = "run_finished" // a run of the process ended
+	EventSkipped     = "skipped"      // an occurrence did not run
+	EventQueued      = "queued"       // an occurrence runs once the current run exits
+	EventReplaced    = "replaced"     // the current run was stopped for a new one
+	EventAllowed     = "allowed"      // an extra instance was started next to the current run
+	EventRetrying    = "retrying"     // a failed attempt of an occurrence will be retried
+	EventFailed      = "failed"       // an occurrence failed for good
+)
+
+// What started a run.
+const (
+	TriggerManual   = "manual"
+	TriggerSchedule = "schedule"
+	TriggerRestart  = "restart"
+	TriggerAPI      = "api"
+)
+
+// Outcomes of a run.
+const (
+	RunRunning   = "running"
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+	RunStopped   = "stopped" // stopped on request, whatever its exit status
+)
+
+// ExitReasonStopped is recorded for a run that ended because it was stopped.
+const ExitReasonStopped = "stopped"
+
+// ExitReasonDaemonRestarted is recorded for a run that was still going when
+// the daemon stopped, so its real outcome is unknown.
+const ExitReasonDaemonRestarted = "daemon_restarted"
+
+// HistoryEntry is one record of a process's history. The history is an
+// append-only JSON Lines file per process holding the start and end of
+// every run and the decisions of its job.
+type HistoryEntry struct {
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event"`
+	Reason     string    `json:"reason,omitempty"`
+	Pid        int       `json:"pid,omitempty"`        // the run the event refers to, if any
+	RunID      string    `json:"run_id,omitempty"`     // likewise
+	Occurrence string    `json:"occurrence,omitempty"` // the scheduled occurrence the event belongs to
+	Attempt    int       `json:"attempt,omitempty"`    // attempt of the occurrence, from 1
+	Trigger    string    `json:"trigger,omitempty"`
+	ExitCode   *int      `json:"exit_code,omitempty"`
+	ExitSignal string    `json:"exit_signal,omitempty"`
+	ExitReason string    `json:"exit_reason,omitempty"`
+}
+
+// Run is one execution of a process, as assembled from its history.
+type Run struct {
+	ID         string     `json:"id"`
+	Trigger    string     `json:"trigger"`
+	Occurrence string     `json:"occurrence,omitempty"` // set for scheduled runs
+	Attempt    int        `json:"attempt,omitempty"`    // attempt of the occurrence, from 1
+	Pid        int        `json:"pid"`
+	Status     string     `json:"status"` // running, succeeded or failed
+	StartTime  time.Time  `json:"start_time"`
+	EndTime    *time.Time `json:"end_time,omitempty"`
+	Duration   Duration   `json:"duration,omitempty"`
+	ExitCode   *int       `json:"exit_code,omitempty"`
+	ExitSignal string     `json:"exit_signal,omitempty"`
+	ExitReason string     `json:"exit_reason,omitempty"`
+	Logs       string     `json:"logs"` // API path of the output captured during the run
+}
+
+// runLogsGrace is added to the end of a run when selecting its output.
+const runLogsGrace = 100 * time.Millisecond
+
+// newRunID returns a unique, time-ordered identifier for a run.
+func newRunID(start time.Time) string {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return start.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+// recordRunStart records the start of a run, described by entry, in the
+// history.
+func (p *Process) recordRunStart(entry HistoryEntry) {
+	entry.Event = EventRunStarted
+	p.recordHistory(entry)
+}
+
+// recordRunEnd records the end of a run in the history.
+func (p *Process) recordRunEnd(runID string, pid int, end time.Time, exitCode int, exitSignal, exitReason string) {
+	p.recordHistory(HistoryEntry{Time: end, Event: EventRunFinished, RunID: runID, Pid: pid, ExitCode: &exitCode, ExitSignal: exitSignal, ExitReason: exitReason})
+}
+
+// historyPath returns the file the job history of the process is kept in.
+func (p *Process) historyPath() string {
+	return filepath.Join(p.manager.config.DataDir, "history", p.Name+".jsonl")
+}
+
+// recordHistory appends an entry to the job history. Failures are logged:
+// losing a history entry must not stop the job itself.
+func (p *Process) recordHistory(entry HistoryEntry) {
+	if entry.Time.IsZero() {
+		entry.Time = p.manager.clock.Now()
+	}
+	if err := appendHistory(p.historyPath(), entry); err != nil {
+		p.manager.logger.Error("failed to record job history", "name", p.Name, "event", entry.Event, "error", err)
+	}
+}
+
+func appendHistory(path string, entry HistoryEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// History returns the history of the process, oldest entry first.
+func (p *Process) History() ([]HistoryEntry, error) {
+	f, err := os.Open(p.historyPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job history: %w", err)
+	}
+	defer f.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue // Skip a line torn by a crash mid-write
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read job history: %w", err)
+	}
+	return entries, nil
+}
+
+// Runs returns the runs of the process, oldest first.
+func (p *Process) Runs() ([]Run, error) {
+	entries, err := p.History()
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []Run
+	index := map[string]int{}
+	for _, entry := range entries {
+		if entry.RunID == "" {
+			continue
+		}
+		switch entry.Event {
+		case EventRunStarted:
+			index[entry.RunID] = len(runs)
+			runs = append(runs, Run{
+				ID:         entry.RunID,
+				Trigger:    entry.Trigger,
+				Occurrence: entry.Occurrence,
+				Attempt:    entry.Attempt,
+				Pid:        entry.Pid,
+				Status:     RunRunning,
+				StartTime:  entry.Time,
+			})
+		case EventRunFinished:
+			i, ok := index[entry.RunID]
+			if !ok {
+				continue
+			}
+			run := &runs[i]
+			end := entry.Time
+			run.EndTime = &end
+			run.Duration = Duration(end.Sub(run.StartTime))
+			run.ExitCode = entry.ExitCode
+			run.ExitSignal = entry.ExitSignal
+			run.ExitReason = entry.ExitReason
+			switch {
+			case entry.ExitReason == ExitReasonStopped:
+				run.Status = RunStopped
+			case entry.ExitCode != nil && *entry.ExitCode == 0 && entry.ExitSignal == "" && entry.ExitReason == "":
+				run.Status = RunSucceeded
+			default:
+				run.Status = RunFailed
+			}
+		}
+	}
+
+	for i := range runs {
+		var next *Run
+		if i+1 < len(runs) {
+			next = &runs[i+1]
+		}
+		runs[i].Logs = p.runLogsPath(&runs[i], next)
+	}
+	return runs, nil
+}
+
+// runLogsPath returns the API path of the output captured during run. The
+// range stops short of the next run, if there is one.
+func (p *Process) runLogsPath(run, next *Run) string {
+	query := url.Values{}
+	// Log lines carry millisecond timestamps taken when they are read, which
+	// can be a moment after the child wrote them or exited.
+	query.Set("since", run.StartTime.Truncate(time.Millisecond).Format(time.RFC3339Nano))
+	if run.EndTime != nil {
+		until := run.EndTime.Add(runLogsGrace)
+		if next != nil && next.StartTime.Truncate(time.Millisecond).Before(until) {
+			until = next.StartTime.Truncate(time.Millisecond).Add(-time.Nanosecond)
+		}
+		query.Set("until", until.Format(time.RFC3339Nano))
+	}
+	return "/v1/processes/" + url.PathEscape(p.Name) + "/logs?" + query.Encode()
+}
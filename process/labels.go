@@ -0,0 +1,40 @@
+package process
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithLabels sets labels of the process, used to select it with MatchLabels.
+func WithLabels(labels map[string]string) ProcessOption {
+	return func(p *Process) error {
+		for key, value := range labels {
+			if !validLabel(key) || (value != "" && !validLabel(value)) {
+				return fmt.Errorf("invalid label '%s=%s'", key, value)
+			}
+		}
+		p.Labels = labels
+		return nil
+	}
+}
+
+// validLabel checks a label key or value, which must not contain the
+// characters that separate a selector.
+func validLabel(s string) bool {
+	return s != "" && !strings.ContainsAny(s, "=, \t\n")
+}
+
+// MatchLabels reports whether labels satisfy a selector: comma-separated
+// key=value requirements that must all hold, such as "team=web,env=prod".
+func MatchLabels(selector string, labels map[string]string) bool {
+	for _, requirement := range strings.Split(selector, ",") {
+		key, value, ok := strings.Cut(requirement, "=")
+		if !ok {
+			return false
+		}
+		if actual, ok := labels[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
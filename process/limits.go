@@ -0,0 +1,102 @@
+package process
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ExitReasonOOMKilled is recorded in Process.ExitReason when the kernel
+// killed the process because its cgroup ran out of memory.
+const ExitReasonOOMKilled = "oom_killed"
+
+// Limits caps the resources a process may use. Resource limits (setrlimit)
+// apply to the child and are inherited by everything it starts; cgroup v2
+// limits apply to the whole cgroup the child is placed in. Unset fields
+// leave the corresponding limit unchanged.
+type Limits struct {
+	OpenFiles    *uint64 `json:"open_files,omitempty"`    // RLIMIT_NOFILE
+	Processes    *uint64 `json:"processes,omitempty"`     // RLIMIT_NPROC, counted per user
+	CoreSize     *uint64 `json:"core_size,omitempty"`     // RLIMIT_CORE in bytes, 0 disables core dumps
+	AddressSpace *uint64 `json:"address_space,omitempty"` // RLIMIT_AS in bytes
+
+	MemoryMax string `json:"memory_max,omitempty"` // memory.max, bytes with optional K/M/G/T suffix or "max"
+	CPUMax    string `json:"cpu_max,omitempty"`    // cpu.max, "<quota|max> [period]" in microseconds
+	PidsMax   string `json:"pids_max,omitempty"`   // pids.max, a number or "max"
+}
+
+// Validate checks that the cgroup values are in the format the kernel expects.
+func (l *Limits) Validate() error {
+	if l.MemoryMax != "" && l.MemoryMax != "max" {
+		if _, err := ParseByteSize(l.MemoryMax); err != nil {
+			return fmt.Errorf("invalid memory_max: %w", err)
+		}
+	}
+	if l.CPUMax != "" {
+		fields := strings.Fields(l.CPUMax)
+		if len(fields) == 0 || len(fields) > 2 {
+			return fmt.Errorf("invalid cpu_max '%s': expected \"<quota|max> [period]\"", l.CPUMax)
+		}
+		if fields[0] != "max" {
+			if _, err := strconv.ParseUint(fields[0], 10, 64); err != nil {
+				return fmt.Errorf("invalid cpu_max quota '%s'", fields[0])
+			}
+		}
+		if len(fields) == 2 {
+			if _, err := strconv.ParseUint(fields[1], 10, 64); err != nil {
+				return fmt.Errorf("invalid cpu_max period '%s'", fields[1])
+			}
+		}
+	}
+	if l.PidsMax != "" && l.PidsMax != "max" {
+		if _, err := strconv.ParseUint(l.PidsMax, 10, 64); err != nil {
+			return fmt.Errorf("invalid pids_max '%s': expected a number or \"max\"", l.PidsMax)
+		}
+	}
+	return nil
+}
+
+// usesCgroup reports whether any cgroup limit is set.
+func (l *Limits) usesCgroup() bool {
+	return l != nil && (l.MemoryMax != "" || l.CPUMax != "" || l.PidsMax != "")
+}
+
+// rlimits returns the resource limits that are set, by field name as in JSON.
+func (l *Limits) rlimits() map[string]uint64 {
+	limits := map[string]uint64{}
+	if l == nil {
+		return limits
+	}
+	for name, value := range map[string]*uint64{"open_files": l.OpenFiles, "processes": l.Processes, "core_size": l.CoreSize, "address_space": l.AddressSpace} {
+		if value != nil {
+			limits[name] = *value
+		}
+	}
+	return limits
+}
+
+// ParseByteSize parses a size such as "1024", "64K", "512M" or "2G" into bytes.
+func ParseByteSize(value string) (uint64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := uint64(1)
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size '%s'", value)
+	}
+	return n * multiplier, nil
+}
@@ -0,0 +1,248 @@
+//go:build linux
+
+package process
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// DefaultCgroupParent is used when config.CgroupParent is empty.
+const DefaultCgroupParent = "/sys/fs/cgroup/exepm"
+
+// rlimitNproc is RLIMIT_NPROC, which the syscall package does not export.
+const rlimitNproc = 6
+
+// cgroupPath returns the cgroup directory of the process.
+func (p *Process) cgroupPath() string {
+	parent := p.manager.config.CgroupParent
+	if parent == "" {
+		parent = DefaultCgroupParent
+	}
+	return filepath.Join(parent, p.Name)
+}
+
+// prepareLimits creates the process's cgroup, writes its limits and makes
+// cmd start inside it. The returned function releases the cgroup
+// descriptor and must be called once cmd has been started (or failed to).
+// The caller must hold the manager lock.
+func (p *Process) prepareLimits(cmd *exec.Cmd) (func(), error) {
+	if !p.Limits.usesCgroup() {
+		return func() {}, nil
+	}
+
+	dir := p.cgroupPath()
+	if err := setupCgroup(dir, p.Limits); err != nil {
+		return nil, err
+	}
+	base, err := cgroupOOMKills(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := syscall.Open(dir, syscall.O_DIRECTORY|syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cgroup %s: %w", dir, err)
+	}
+	// The child is created inside the cgroup, so it never runs unconstrained.
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = fd
+	p.oomKillsBase = base
+	return func() { syscall.Close(fd) }, nil
+}
+
+// setupCgroup creates dir below its parent, enables the controllers the
+// limits need on the parent and writes the limits.
+func setupCgroup(dir string, limits *Limits) error {
+	parent := filepath.Dir(dir)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return fmt.Errorf("failed to create cgroup parent %s: %w", parent, err)
+	}
+
+	files := []struct{ controller, file, value string }{
+		{"memory", "memory.max", limits.MemoryMax},
+		{"cpu", "cpu.max", limits.CPUMax},
+		{"pids", "pids.max", limits.PidsMax},
+	}
+	for _, f := range files {
+		if f.value == "" {
+			continue
+		}
+		if err := writeCgroupFile(filepath.Join(parent, "cgroup.subtree_control"), "+"+f.controller); err != nil {
+			return fmt.Errorf("failed to enable the %s controller in %s: %w", f.controller, parent, err)
+		}
+	}
+
+	if err := os.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
+		return fmt.Errorf("failed to create cgroup %s: %w", dir, err)
+	}
+	for _, f := range files {
+		value := f.value
+		if value == "" {
+			value = "max" // Clear a limit left over from an earlier definition
+		}
+		if err := writeCgroupFile(filepath.Join(dir, f.file), value); err != nil {
+			if f.value == "" && os.IsNotExist(err) {
+				continue // Controller not enabled and nothing to set
+			}
+			return fmt.Errorf("failed to set %s: %w", f.file, err)
+		}
+	}
+	return nil
+}
+
+// writeCgroupFile writes a single value to a cgroup interface file.
+func writeCgroupFile(path, value string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.WriteString(value)
+	return err
+}
+
+// cgroupOOMKills returns the oom_kill counter from memory.events, or 0 when
+// the memory controller is not enabled for the cgroup.
+func cgroupOOMKills(dir string) (int64, error) {
+	file, err := os.Open(filepath.Join(dir, "memory.events"))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), " "); ok && key == "oom_kill" {
+			return strconv.ParseInt(value, 10, 64)
+		}
+	}
+	return 0, scanner.Err()
+}
+
+// oomKilled reports whether the cgroup recorded an OOM kill since the
+// current run started. The caller must hold the manager lock.
+func (p *Process) oomKilled() bool {
+	if !p.Limits.usesCgroup() {
+		return false
+	}
+	kills, err := cgroupOOMKills(p.cgroupPath())
+	return err == nil && kills > p.oomKillsBase
+}
+
+// removeCgroup deletes the process's cgroup if it has one. The kernel only
+// allows this once no process is left in it.
+func (p *Process) removeCgroup() error {
+	if !p.Limits.usesCgroup() {
+		return nil
+	}
+	if err := syscall.Rmdir(p.cgroupPath()); err != nil && err != syscall.ENOENT {
+		return err
+	}
+	return nil
+}
+
+// rlimitHelper is the argv[0] with which the manager runs itself to apply
+// resource limits. Go cannot run code between fork and exec, so the child
+// first executes the manager binary, which sets the limits on itself and
+// then executes the real program in place. The program keeps the PID,
+// process group and cgroup, and never runs without its limits.
+const rlimitHelper = "exepm-rlimit-helper"
+
+// rlimitResources maps the names of the Limits fields set with setrlimit to
+// their resources.
+var rlimitResources = map[string]int{
+	"open_files":    syscall.RLIMIT_NOFILE,
+	"processes":     rlimitNproc,
+	"core_size":     syscall.RLIMIT_CORE,
+	"address_space": syscall.RLIMIT_AS,
+}
+
+func init() {
+	if len(os.Args) > 0 && os.Args[0] == rlimitHelper {
+		os.Exit(runRlimitHelper(os.Args[1:]))
+	}
+}
+
+// prepareRlimits makes cmd start through the rlimit helper if the process
+// has resource limits. The returned function must be called once cmd.Start
+// has returned; after a successful start it waits until the helper has
+// executed the program and returns the error it reported, if any.
+func (p *Process) prepareRlimits(cmd *exec.Cmd) (func() error, error) {
+	var limits []string
+	for name, value := range p.Limits.rlimits() {
+		limits = append(limits, name+"="+strconv.FormatUint(value, 10))
+	}
+	if len(limits) == 0 {
+		return func() error { return nil }, nil
+	}
+
+	// The helper reports failures on fd 3, which it marks close-on-exec:
+	// reading end of file without data means the program was executed.
+	report, reportW, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pipe for resource limits: %w", err)
+	}
+	cmd.Args = append(append(append([]string{rlimitHelper}, limits...), "--", cmd.Path), cmd.Args...)
+	cmd.Path = "/proc/self/exe"
+	cmd.ExtraFiles = append(cmd.ExtraFiles, reportW)
+
+	return func() error {
+		reportW.Close()
+		defer report.Close()
+		if cmd.Process == nil {
+			return nil // Not started, cmd.Start has the error
+		}
+		msg, err := io.ReadAll(report)
+		if err != nil {
+			return fmt.Errorf("failed to read resource limits report: %w", err)
+		}
+		if len(msg) > 0 {
+			return errors.New(string(msg))
+		}
+		return nil
+	}, nil
+}
+
+// runRlimitHelper runs in the child started by prepareRlimits. It sets the
+// limits given as name=value arguments and executes the program following
+// "--", returning an exit code only if that fails.
+func runRlimitHelper(args []string) int {
+	report := os.NewFile(3, "rlimit-report")
+	syscall.CloseOnExec(3)
+	fail := func(err error) int {
+		fmt.Fprint(report, err.Error())
+		return 127
+	}
+
+	sep := slices.Index(args, "--")
+	if sep < 0 || len(args) < sep+3 {
+		return fail(errors.New("rlimit helper: missing program"))
+	}
+	for _, arg := range args[:sep] {
+		name, value, _ := strings.Cut(arg, "=")
+		resource, ok := rlimitResources[name]
+		limit, err := strconv.ParseUint(value, 10, 64)
+		if !ok || err != nil {
+			return fail(fmt.Errorf("rlimit helper: invalid limit '%s'", arg))
+		}
+		// Raising a hard limit needs privileges the child may have dropped.
+		if err := syscall.Setrlimit(resource, &syscall.Rlimit{Cur: limit, Max: limit}); err != nil {
+			return fail(fmt.Errorf("failed to set %s limit: %w", name, err))
+		}
+	}
+	err := syscall.Exec(args[sep+1], args[sep+2:], os.Environ())
+	return fail(fmt.Errorf("failed to start process executable: %w", err))
+}
@@ -0,0 +1,102 @@
+//go:build linux
+
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResourceLimits(t *testing.T) {
+	pm := setupTestManager(t)
+	openFiles, coreSize := uint64(64), uint64(0)
+	p, err := pm.AddProcess("limited", "/bin/sh", 0, WithLimits(Limits{OpenFiles: &openFiles, CoreSize: &coreSize}))
+	if err != nil {
+		t.Fatalf("failed to add process: %v", err)
+	}
+	// The limits are in place before the program runs its first instruction.
+	if err := p.Start("-c", `echo "$(ulimit -n) $(ulimit -c)"`); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	waitForExit(t, p)
+	time.Sleep(50 * time.Millisecond) // Let the output reach the log
+
+	lines, _ := p.TailLogs(1, "stdout")
+	if len(lines) != 1 || lines[0].Text != "64 0" {
+		t.Errorf("expected limits '64 0' in the child, got %+v", lines)
+	}
+
+	// A limit the kernel refuses fails the start instead of running the child without it.
+	tooMany := uint64(1 << 62)
+	refused, err := pm.AddProcess("refused", "/bin/sh", 0, WithLimits(Limits{OpenFiles: &tooMany}))
+	if err != nil {
+		t.Fatalf("failed to add process: %v", err)
+	}
+	if err := refused.Start("-c", "sleep 10"); err == nil || !strings.Contains(err.Error(), "open_files") {
+		t.Errorf("expected the refused limit to fail the start, got %v", err)
+	}
+	if refused.IsRunning() {
+		t.Error("process is running without its limits")
+	}
+
+	for _, bad := range []Limits{{MemoryMax: "lots"}, {CPUMax: "half"}, {PidsMax: "-1"}} {
+		if _, err := pm.AddProcess("bad-limits", "/bin/true", 0, WithLimits(bad)); err == nil {
+			t.Errorf("invalid limits %+v were accepted", bad)
+		}
+	}
+}
+
+func TestCgroupSetup(t *testing.T) {
+	// A fake cgroup tree: the kernel would create the interface files itself.
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "svc")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{filepath.Join(parent, "cgroup.subtree_control"), filepath.Join(dir, "memory.max"), filepath.Join(dir, "cpu.max"), filepath.Join(dir, "pids.max")} {
+		if err := os.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "memory.events"), []byte("low 0\nhigh 0\nmax 3\noom 1\noom_kill 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := setupCgroup(dir, &Limits{MemoryMax: "64M", PidsMax: "32"}); err != nil {
+		t.Fatalf("failed to set up cgroup: %v", err)
+	}
+	for file, want := range map[string]string{"memory.max": "64M", "cpu.max": "max", "pids.max": "32"} {
+		if data, _ := os.ReadFile(filepath.Join(dir, file)); string(data) != want {
+			t.Errorf("expected %s to be %q, got %q", file, want, data)
+		}
+	}
+	if kills, err := cgroupOOMKills(dir); err != nil || kills != 2 {
+		t.Errorf("expected 2 OOM kills, got %d (%v)", kills, err)
+	}
+
+	// Run a real child in a cgroup where cgroup v2 with the memory controller is available.
+	controllers, err := os.ReadFile("/sys/fs/cgroup/cgroup.controllers")
+	if err != nil || !strings.Contains(string(controllers), "memory") || os.Getuid() != 0 {
+		t.Skip("cgroup v2 with the memory controller is not available")
+	}
+	pm := setupTestManager(t)
+	pm.config.CgroupParent = filepath.Join("/sys/fs/cgroup", "exepm-test")
+	p, err := pm.AddProcess("in-cgroup", "/bin/sh", 0, WithLimits(Limits{MemoryMax: "32M", PidsMax: "16"}))
+	if err != nil {
+		t.Fatalf("failed to add process: %v", err)
+	}
+	defer os.Remove(pm.config.CgroupParent)
+	defer pm.RemoveProcess("in-cgroup")
+	if err := p.Start("-c", "cat /proc/self/cgroup"); err != nil {
+		t.Fatalf("failed to start process in cgroup: %v", err)
+	}
+	waitForExit(t, p)
+	time.Sleep(50 * time.Millisecond)
+	lines, _ := p.TailLogs(1, "stdout")
+	if len(lines) != 1 || !strings.HasSuffix(lines[0].Text, "/exepm-test/in-cgroup") {
+		t.Errorf("child was not placed in its cgroup: %+v", lines)
+	}
+}
@@ -0,0 +1,34 @@
+//go:build !linux
+
+package process
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// prepareLimits rejects cgroup limits, which only exist on Linux.
+func (p *Process) prepareLimits(cmd *exec.Cmd) (func(), error) {
+	if p.Limits.usesCgroup() {
+		return nil, fmt.Errorf("cgroup limits are only supported on Linux")
+	}
+	return func() {}, nil
+}
+
+// oomKilled always reports false without cgroups.
+func (p *Process) oomKilled() bool {
+	return false
+}
+
+// removeCgroup has nothing to remove without cgroups.
+func (p *Process) removeCgroup() error {
+	return nil
+}
+
+// prepareRlimits rejects resource limits, which are only applied on Linux.
+func (p *Process) prepareRlimits(cmd *exec.Cmd) (func() error, error) {
+	if len(p.Limits.rlimits()) > 0 {
+		return nil, fmt.Errorf("resource limits are only supported on Linux")
+	}
+	return func() error { return nil }, nil
+}
@@ -0,0 +1,528 @@
+package process
+
+import (
+	"ExeProcessManager/config"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Names and formats used for captured process output.
+const (
+	OutputLogName   = "output.log"
+	outputTimestamp = "2006-01-02T15:04:05.000Z07:00"
+	rotatedStamp    = "20060102-150405.000"
+
+	defaultLogMaxSizeMB = 10
+	defaultLogRetention = 5
+	maxLogLineLength    = 64 * 1024
+)
+
+// outputLog writes the interleaved stdout/stderr of one process to
+// DataDir/logs/<name>/output.log, one line per write:
+//
+//	2006-01-02T15:04:05.000Z07:00 stdout the line as printed
+//
+// and rotates the file by size and age. It is shared by all runs of the
+// process and safe for concurrent use.
+type outputLog struct {
+	mu        sync.Mutex
+	dir       string
+	file      *os.File
+	size      int64
+	opened    time.Time
+	maxSize   int64
+	maxAge    time.Duration
+	compress  bool
+	retention int
+	closed    bool
+	logError  func(msg string, args ...any)
+
+	pending  []string       // Rotated files still to compress and prune, oldest first
+	archived sync.WaitGroup // Done once a pending file has been handled
+
+	subscribers map[chan LogLine]string // Followers and their stream filter ("" for all)
+}
+
+// LogLine is one captured line of process output.
+type LogLine struct {
+	Time   time.Time `json:"time"`
+	Stream string    `json:"stream"` // stdout or stderr
+	Text   string    `json:"text"`
+}
+
+// String formats the line the way it is stored in the log file.
+func (l LogLine) String() string {
+	return l.Time.Format(outputTimestamp) + " " + l.Stream + " " + l.Text
+}
+
+// parseLogLine parses a line written by outputLog.writeLine.
+func parseLogLine(line string) (LogLine, bool) {
+	fields := strings.SplitN(line, " ", 3)
+	if len(fields) < 2 {
+		return LogLine{}, false
+	}
+	ts, err := time.Parse(outputTimestamp, fields[0])
+	if err != nil {
+		return LogLine{}, false
+	}
+	entry := LogLine{Time: ts, Stream: fields[1]}
+	if len(fields) == 3 {
+		entry.Text = fields[2]
+	}
+	return entry, true
+}
+
+// ValidateStream checks a stream filter, which may be empty for both streams.
+func ValidateStream(stream string) error {
+	switch stream {
+	case "", "stdout", "stderr":
+		return nil
+	default:
+		return errorf(ErrInvalidSpec, "invalid stream '%s': must be stdout or stderr", stream)
+	}
+}
+
+// newOutputLog prepares the log directory and applies the rotation settings.
+func newOutputLog(dir string, cfg config.OutputLogConfig, logError func(string, ...any)) (*outputLog, error) {
+	maxAge, err := cfg.MaxAgeDuration()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	l := &outputLog{
+		dir:       dir,
+		maxSize:   int64(cfg.MaxSizeMB) * 1024 * 1024,
+		maxAge:    maxAge,
+		compress:  cfg.Compress,
+		retention: cfg.Retention,
+		logError:  logError,
+	}
+	if l.maxSize <= 0 {
+		l.maxSize = defaultLogMaxSizeMB * 1024 * 1024
+	}
+	if l.retention <= 0 {
+		l.retention = defaultLogRetention
+	}
+	return l, nil
+}
+
+// attach returns the write end of a pipe to hand to the child as the given
+// stream. Everything written to it is copied into the log, line by line,
+// until every holder of the write end has closed it.
+func (l *outputLog) attach(stream string) (*os.File, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s pipe: %w", stream, err)
+	}
+
+	go func() {
+		defer r.Close()
+		reader := bufio.NewReaderSize(r, maxLogLineLength)
+		for {
+			// Over-long lines come back in chunks and are logged as separate lines.
+			line, _, err := reader.ReadLine()
+			if len(line) > 0 || err == nil {
+				l.writeLine(stream, line)
+			}
+			if err != nil {
+				if err != io.EOF {
+					l.logError("failed to read process output", "stream", stream, "error", err)
+				}
+				return
+			}
+		}
+	}()
+	return w, nil
+}
+
+// writeLine appends one tagged, timestamped line, rotating first if needed.
+func (l *outputLog) writeLine(stream string, line []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return
+	}
+
+	now := time.Now()
+	entry := make([]byte, 0, len(outputTimestamp)+len(stream)+len(line)+3)
+	entry = now.AppendFormat(entry, outputTimestamp)
+	entry = append(entry, ' ')
+	entry = append(entry, stream...)
+	entry = append(entry, ' ')
+	entry = append(entry, line...)
+	entry = append(entry, '\n')
+
+	if l.file != nil && l.needsRotation(now, len(entry)) {
+		if err := l.rotate(now); err != nil {
+			l.logError("failed to rotate output log", "dir", l.dir, "error", err)
+		}
+	}
+	if l.file == nil {
+		if err := l.open(now); err != nil {
+			l.logError("failed to open output log", "dir", l.dir, "error", err)
+			return
+		}
+	}
+
+	n, err := l.file.Write(entry)
+	l.size += int64(n)
+	if err != nil {
+		l.logError("failed to write output log", "dir", l.dir, "error", err)
+	}
+
+	// Followers that cannot keep up miss lines rather than blocking the child.
+	logLine := LogLine{Time: now, Stream: stream, Text: string(line)}
+	for ch, filter := range l.subscribers {
+		if filter == "" || filter == stream {
+			select {
+			case ch <- logLine:
+			default:
+			}
+		}
+	}
+}
+
+// subscribe registers a follower for lines of the given stream ("" for
+// both). The channel is closed by the returned cancel function or when the
+// log is closed.
+func (l *outputLog) subscribe(stream string) (<-chan LogLine, func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ch := make(chan LogLine, 256)
+	if l.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if l.subscribers == nil {
+		l.subscribers = make(map[chan LogLine]string)
+	}
+	l.subscribers[ch] = stream
+
+	cancel := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if _, ok := l.subscribers[ch]; ok {
+			delete(l.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel
+}
+
+// needsRotation reports whether writing n more bytes at now should go to a
+// fresh file. A file is never rotated while it is empty.
+func (l *outputLog) needsRotation(now time.Time, n int) bool {
+	if l.size == 0 {
+		return false
+	}
+	if l.size+int64(n) > l.maxSize {
+		return true
+	}
+	return l.maxAge > 0 && now.Sub(l.opened) >= l.maxAge
+}
+
+// open opens the active file for appending. An existing file counts as
+// opened at its last modification so that age-based rotation survives
+// daemon restarts.
+func (l *outputLog) open(now time.Time) error {
+	file, err := os.OpenFile(filepath.Join(l.dir, OutputLogName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	l.file = file
+	l.size = info.Size()
+	l.opened = now
+	if l.size > 0 {
+		l.opened = info.ModTime()
+	}
+	return nil
+}
+
+// rotate closes the active file and renames it with a timestamp. Rotated
+// files are compressed, if configured, and pruned to the retention count in
+// the background, so writers are not held up. The caller must hold l.mu.
+func (l *outputLog) rotate(now time.Time) error {
+	l.file.Close()
+	l.file = nil
+	l.size = 0
+
+	rotated := l.rotatedName(now)
+	if err := os.Rename(filepath.Join(l.dir, OutputLogName), rotated); err != nil {
+		return err
+	}
+	l.archived.Add(1)
+	l.pending = append(l.pending, rotated)
+	if len(l.pending) == 1 {
+		go l.archive() // Otherwise the running one picks it up
+	}
+	return nil
+}
+
+// archive compresses and prunes the files queued by rotate, one after
+// another, until none is left.
+func (l *outputLog) archive() {
+	for {
+		l.mu.Lock()
+		if len(l.pending) == 0 {
+			l.mu.Unlock()
+			return
+		}
+		path := l.pending[0]
+		l.mu.Unlock()
+
+		// Pruning may already have removed a file that was queued behind others.
+		if l.compress && FileExists(path) {
+			if err := gzipFile(path); err != nil {
+				l.logError("failed to compress rotated output log", "path", path, "error", err)
+			}
+		}
+		// Retention applies even if compression failed.
+		if err := l.prune(); err != nil {
+			l.logError("failed to remove old output logs", "dir", l.dir, "error", err)
+		}
+
+		l.mu.Lock()
+		l.pending = l.pending[1:]
+		l.mu.Unlock()
+		l.archived.Done()
+	}
+}
+
+// rotatedName returns a free name for a file rotated at now. Files rotated
+// within the same millisecond get increasing sequence numbers, so none
+// replaces another and the names still sort chronologically.
+func (l *outputLog) rotatedName(now time.Time) string {
+	prefix := filepath.Join(l.dir, "output-"+now.Format(rotatedStamp))
+	for seq := 0; ; seq++ {
+		name := fmt.Sprintf("%s-%03d.log", prefix, seq)
+		if !FileExists(name) && !FileExists(name+".gz") {
+			return name
+		}
+	}
+}
+
+// prune deletes the oldest rotated files so that at most retention remain.
+func (l *outputLog) prune() error {
+	rotated, err := RotatedLogs(l.dir)
+	if err != nil {
+		return err
+	}
+	for len(rotated) > l.retention {
+		if err := os.Remove(rotated[0]); err != nil {
+			return err
+		}
+		rotated = rotated[1:]
+	}
+	return nil
+}
+
+// Close closes the active file. Output arriving afterwards is dropped.
+func (l *outputLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.closed = true
+	for ch := range l.subscribers {
+		close(ch)
+	}
+	l.subscribers = nil
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// RotatedLogs lists the rotated output files in dir, oldest first.
+func RotatedLogs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var rotated []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if name != OutputLogName && strings.HasPrefix(name, "output-") &&
+			(strings.HasSuffix(name, ".log") || strings.HasSuffix(name, ".log.gz")) {
+			rotated = append(rotated, filepath.Join(dir, name))
+		}
+	}
+	// The timestamp in the name sorts chronologically.
+	sort.Strings(rotated)
+	return rotated, nil
+}
+
+// gzipFile compresses path into path.gz and removes the original.
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		zw.Close()
+		dst.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// LogDir returns the directory holding the captured output of the process.
+func (p *Process) LogDir() string {
+	return filepath.Join(p.manager.config.DataDir, "logs", p.Name)
+}
+
+// outputLog returns the process's output log, creating it on first use.
+// The caller must hold the manager lock.
+func (p *Process) outputLog() (*outputLog, error) {
+	if p.output == nil {
+		out, err := newOutputLog(p.LogDir(), p.manager.config.OutputLogs, p.manager.logger.With("name", p.Name).Error)
+		if err != nil {
+			return nil, err
+		}
+		p.output = out
+	}
+	return p.output, nil
+}
+
+// TailLogs returns up to the last n captured lines, oldest first, optionally
+// limited to one stream. Rotated files are read when the active file holds
+// fewer than n matching lines.
+func (p *Process) TailLogs(n int, stream string) ([]LogLine, error) {
+	return p.readLogs(n, stream, time.Time{}, time.Time{})
+}
+
+// LogsBetween returns up to the last n lines captured between since and
+// until, oldest first, optionally limited to one stream. A zero time leaves
+// that end of the range open, and a negative n returns every line.
+func (p *Process) LogsBetween(since, until time.Time, n int, stream string) ([]LogLine, error) {
+	return p.readLogs(n, stream, since, until)
+}
+
+// readLogs reads the active and rotated output logs, newest file first,
+// until n lines matching the filters are found.
+func (p *Process) readLogs(n int, stream string, since, until time.Time) ([]LogLine, error) {
+	if n < 0 {
+		n = math.MaxInt
+	}
+	if err := ValidateStream(stream); err != nil {
+		return nil, err
+	}
+	dir := p.LogDir()
+	files := []string{filepath.Join(dir, OutputLogName)}
+	if rotated, err := RotatedLogs(dir); err == nil {
+		for i := len(rotated) - 1; i >= 0; i-- {
+			files = append(files, rotated[i])
+		}
+	}
+
+	var lines []LogLine
+	for _, path := range files {
+		if len(lines) >= n {
+			break
+		}
+		fileLines, err := readLogFile(path, stream)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		if !since.IsZero() || !until.IsZero() {
+			fileLines = slices.DeleteFunc(fileLines, func(line LogLine) bool {
+				return line.Time.Before(since) || (!until.IsZero() && line.Time.After(until))
+			})
+		}
+		lines = append(fileLines, lines...)
+	}
+
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
+// FollowLogs returns a channel that receives every line captured from now
+// on, optionally limited to one stream, and a function that stops following.
+// The channel is closed when the process is removed.
+func (p *Process) FollowLogs(stream string) (<-chan LogLine, func(), error) {
+	if err := ValidateStream(stream); err != nil {
+		return nil, nil, err
+	}
+
+	p.manager.processMutex.Lock()
+	out, err := p.outputLog()
+	p.manager.processMutex.Unlock()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ch, cancel := out.subscribe(stream)
+	return ch, cancel, nil
+}
+
+// readLogFile reads all lines of a plain or gzipped output log that match
+// the stream filter.
+func readLogFile(path, stream string) ([]LogLine, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open compressed log %s: %w", path, err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	var lines []LogLine
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 2*maxLogLineLength)
+	for scanner.Scan() {
+		line, ok := parseLogLine(scanner.Text())
+		if ok && (stream == "" || line.Stream == stream) {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
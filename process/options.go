@@ -0,0 +1,186 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// ProcessOption configures optional settings of a process when it is added.
+type ProcessOption func(*Process) error
+
+// WithRestartPolicy sets the policy applied when the process exits on its own.
+func WithRestartPolicy(policy RestartPolicy) ProcessOption {
+	return func(p *Process) error {
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+		p.Restart = &policy
+		return nil
+	}
+}
+
+// WithStopSignal sets the signal Stop sends before escalating to SIGKILL.
+func WithStopSignal(signal string) ProcessOption {
+	return func(p *Process) error {
+		_, name, err := parseStopSignal(signal)
+		if err != nil {
+			return err
+		}
+		p.StopSignal = name
+		return nil
+	}
+}
+
+// WithStopTimeout sets how long Stop waits for the process to exit after the
+// stop signal before killing it.
+func WithStopTimeout(timeout time.Duration) ProcessOption {
+	return func(p *Process) error {
+		if timeout < 0 {
+			return fmt.Errorf("stop timeout must not be negative")
+		}
+		p.StopTimeout = Duration(timeout)
+		return nil
+	}
+}
+
+// WithEnv sets environment variables for the child. They override values
+// inherited from the manager and loaded from env files.
+func WithEnv(env map[string]string) ProcessOption {
+	return func(p *Process) error {
+		for key := range env {
+			if !validEnvKey(key) {
+				return fmt.Errorf("invalid environment variable name '%s'", key)
+			}
+		}
+		p.Env = env
+		return nil
+	}
+}
+
+// WithEnvFiles sets dotenv files that are loaded every time the process starts.
+func WithEnvFiles(paths ...string) ProcessOption {
+	return func(p *Process) error {
+		for _, path := range paths {
+			if _, err := LoadEnvFile(path); err != nil {
+				return err
+			}
+		}
+		p.EnvFiles = paths
+		return nil
+	}
+}
+
+// WithDir sets the working directory of the child.
+func WithDir(dir string) ProcessOption {
+	return func(p *Process) error {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("invalid working directory: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("invalid working directory: %s is not a directory", dir)
+		}
+		p.Dir = dir
+		return nil
+	}
+}
+
+// WithInheritEnv controls whether the child starts from the manager's
+// environment. It does by default.
+func WithInheritEnv(inherit bool) ProcessOption {
+	return func(p *Process) error {
+		p.InheritEnv = inherit
+		return nil
+	}
+}
+
+// WithArgs sets the default arguments used whenever the process starts
+// without arguments of its own, including scheduled starts.
+func WithArgs(args ...string) ProcessOption {
+	return func(p *Process) error {
+		p.Args = args
+		return nil
+	}
+}
+
+// WithUser runs the process as the given user name or uid.
+func WithUser(name string) ProcessOption {
+	return func(p *Process) error {
+		if _, err := lookupUser(name); err != nil {
+			return err
+		}
+		p.User = name
+		return nil
+	}
+}
+
+// WithGroup runs the process with the given primary group name or gid.
+func WithGroup(name string) ProcessOption {
+	return func(p *Process) error {
+		if _, err := lookupGroupID(name); err != nil {
+			return err
+		}
+		p.Group = name
+		return nil
+	}
+}
+
+// WithSupplementaryGroups sets the supplementary groups of the process.
+func WithSupplementaryGroups(names ...string) ProcessOption {
+	return func(p *Process) error {
+		for _, name := range names {
+			if _, err := lookupGroupID(name); err != nil {
+				return err
+			}
+		}
+		p.SupplementaryGroups = names
+		return nil
+	}
+}
+
+// WithLimits sets resource and cgroup limits applied whenever the process starts.
+func WithLimits(limits Limits) ProcessOption {
+	return func(p *Process) error {
+		if err := limits.Validate(); err != nil {
+			return err
+		}
+		p.Limits = &limits
+		return nil
+	}
+}
+
+// Reconfigure replaces the configuration of the process as if it were added
+// again with path, schedul and opts. Its state, job and history are kept; a
+// running process picks up the new configuration when it next starts.
+func (p *Process) Reconfigure(path string, schedul int, opts ...ProcessOption) error {
+	next := p.manager.NewProcess(p.Name, path, schedul)
+	for _, opt := range opts {
+		if err := opt(next); err != nil {
+			return errorf(ErrInvalidSpec, "invalid option for process '%s': %w", p.Name, err)
+		}
+	}
+
+	p.manager.processMutex.Lock()
+	defer p.manager.processMutex.Unlock()
+
+	if schedul != 1 && p.Timing != nil {
+		return errorf(ErrInUse, "process '%s' has a job, unset it before making the process manual", p.Name)
+	}
+	p.Path, p.Schedul, p.Labels = next.Path, next.Schedul, next.Labels
+	p.Args, p.Env, p.EnvFiles, p.Dir, p.InheritEnv = next.Args, next.Env, next.EnvFiles, next.Dir, next.InheritEnv
+	p.User, p.Group, p.SupplementaryGroups = next.User, next.Group, next.SupplementaryGroups
+	p.Limits, p.Restart = next.Limits, next.Restart
+	p.StopSignal, p.StopTimeout = next.StopSignal, next.StopTimeout
+
+	p.manager.logger.Info("process reconfigured", "name", p.Name, "path", p.Path)
+	return p.SaveState()
+}
+
+// Snapshot returns a copy of the process taken under the manager lock, safe
+// to read or encode while the process keeps running.
+func (p *Process) Snapshot() Process {
+	p.manager.processMutex.Lock()
+	defer p.manager.processMutex.Unlock()
+	return *p
+}
@@ -2,8 +2,17 @@ package process
 
 import (
 	"ExeProcessManager/config"
+	"encoding/json"
+	"errors"
 	"log/slog"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -79,7 +88,1402 @@ func TestAddProcessDuplicate(t *testing.T) {
 	}
 
 	_, err = pm.AddProcess("duplicate", "/bin/true", 0)
-	if err == nil {
-		t.Fatal("second add with same name should fail, but it succeeded")
+	if !errors.Is(err, ErrAlreadyExists) {
+		t.Fatalf("second add with same name should fail as already existing, got %v", err)
 	}
-}
\ No newline at end of file
+}
+
+func TestErrorKinds(t *testing.T) {
+	pm := setupTestManager(t)
+	manual, err := pm.AddProcess("manual", "sleep", 0, WithArgs("5"))
+	if err != nil {
+		t.Fatalf("failed to add process: %v", err)
+	}
+	scheduled, err := pm.AddProcess("scheduled", "/bin/true", 1)
+	if err != nil {
+		t.Fatalf("failed to add process: %v", err)
+	}
+	if err := pm.CreateTimingRule("hourly", "every 1h"); err != nil {
+		t.Fatalf("failed to create timing rule: %v", err)
+	}
+	if err := scheduled.SetJob("hourly"); err != nil {
+		t.Fatalf("failed to set job: %v", err)
+	}
+	t.Cleanup(func() { manual.Stop() })
+
+	_, errMissing := pm.GetProcessByName("missing")
+	_, errRule := pm.TimingRule("missing")
+	_, errOption := pm.AddProcess("bad", "/bin/true", 0, WithStopTimeout(-time.Second))
+	_, errName := pm.AddProcess("../escaped", "/bin/true", 0)
+	errStopped := manual.Stop()
+	if err := manual.Start(); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"unknown process", errMissing, ErrNotFound},
+		{"unknown rule", errRule, ErrNotFound},
+		{"existing rule", pm.CreateTimingRule("hourly", "every 2h"), ErrAlreadyExists},
+		{"running process", manual.Start(), ErrAlreadyRunning},
+		{"stopped process", errStopped, ErrNotRunning},
+		{"manual start of a scheduled process", scheduled.Start(), ErrScheduledOnly},
+		{"job of a manual process", manual.SetJob("hourly"), ErrNotScheduled},
+		{"rule used by a job", pm.DeleteTimingRule("hourly"), ErrInUse},
+		{"invalid option", errOption, ErrInvalidSpec},
+		{"invalid schedule", pm.CreateTimingRule("bad", "whenever"), ErrInvalidSpec},
+		{"process name leaving its directory", errName, ErrInvalidSpec},
+		{"rule name leaving its directory", pm.CreateTimingRule("../../escaped", "every 1h"), ErrInvalidSpec},
+		{"deleting a rule outside its directory", pm.DeleteTimingRule("../rules/hourly"), ErrInvalidSpec},
+		{"unknown signal", manual.Signal("SIGBOGUS"), ErrInvalidSpec},
+	}
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.kind) {
+			t.Errorf("%s: expected an error of kind %q, got %v", tt.name, tt.kind, tt.err)
+		}
+	}
+}
+
+func TestMatchLabels(t *testing.T) {
+	labels := map[string]string{"team": "web", "env": "prod"}
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"team=web", true},
+		{"team=web,env=prod", true},
+		{"team=web,env=dev", false},
+		{"team=data", false},
+		{"owner=web", false},
+	}
+	for _, tt := range tests {
+		if got := MatchLabels(tt.selector, labels); got != tt.want {
+			t.Errorf("MatchLabels(%q) = %v, want %v", tt.selector, got, tt.want)
+		}
+	}
+
+	pm := setupTestManager(t)
+	if _, err := pm.AddProcess("bad", "/bin/true", 0, WithLabels(map[string]string{"team,env": "web"})); !errors.Is(err, ErrInvalidSpec) {
+		t.Errorf("expected a label key with a separator to be rejected, got %v", err)
+	}
+}
+
+// waitForExit polls until the exit watcher has moved p out of the running state.
+func waitForExit(t *testing.T, p *Process) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for p.IsRunning() {
+		if time.Now().After(deadline) {
+			t.Fatalf("process '%s' was still running after 5s", p.Name)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestProcessExitWatcher(t *testing.T) {
+	pm := setupTestManager(t)
+
+	ok, _ := pm.AddProcess("clean-exit", "/bin/sh", 0)
+	if err := ok.Start("-c", "exit 0"); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	waitForExit(t, ok)
+	if ok.Stat != StatStopped || ok.ExitCode != 0 || ok.Pid != 0 {
+		t.Errorf("expected clean stop, got stat=%d code=%d pid=%d", ok.Stat, ok.ExitCode, ok.Pid)
+	}
+
+	bad, _ := pm.AddProcess("bad-exit", "/bin/sh", 0)
+	if err := bad.Start("-c", "exit 3"); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	waitForExit(t, bad)
+	if bad.Stat != StatCrashed || bad.ExitCode != 3 {
+		t.Errorf("expected crash with code 3, got stat=%d code=%d", bad.Stat, bad.ExitCode)
+	}
+	if bad.GetStatus() != "crashed" {
+		t.Errorf("expected status 'crashed', got '%s'", bad.GetStatus())
+	}
+	if bad.EndTime.IsZero() {
+		t.Error("end time was not recorded")
+	}
+
+	// The recorded outcome must survive a reload from disk.
+	reloaded := setupTestManager(t)
+	reloaded.config = pm.config
+	if err := reloaded.LoadProcessesFromDisk(); err != nil {
+		t.Fatalf("failed to reload processes: %v", err)
+	}
+	p, err := reloaded.GetProcessByName("bad-exit")
+	if err != nil {
+		t.Fatalf("process not reloaded: %v", err)
+	}
+	if p.Stat != StatCrashed || p.ExitCode != 3 {
+		t.Errorf("reloaded state lost exit details: stat=%d code=%d", p.Stat, p.ExitCode)
+	}
+}
+
+func TestRestartPolicyCrashLoop(t *testing.T) {
+	pm := setupTestManager(t)
+	retries := 2
+	policy := RestartPolicy{
+		Mode:        RestartOnFailure,
+		MaxRetries:  &retries,
+		BackoffBase: Duration(10 * time.Millisecond),
+		BackoffCap:  Duration(20 * time.Millisecond),
+		StableAfter: Duration(time.Hour),
+	}
+	p, err := pm.AddProcess("flapper", "/bin/sh", 0, WithRestartPolicy(policy))
+	if err != nil {
+		t.Fatalf("failed to add process: %v", err)
+	}
+	if err := p.Start("-c", "exit 1"); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for p.GetStatus() != "fatal" {
+		if time.Now().After(deadline) {
+			t.Fatalf("process never became fatal, status is '%s'", p.GetStatus())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if p.Restarts != 2 {
+		t.Errorf("expected 2 restarts before giving up, got %d", p.Restarts)
+	}
+
+	// A manual start clears the fatal state and the retry counter.
+	if err := p.Start("-c", "sleep 5"); err != nil {
+		t.Fatalf("manual start after fatal failed: %v", err)
+	}
+	if p.Restarts != 0 || !p.IsRunning() {
+		t.Errorf("manual start did not reset state: restarts=%d status=%s", p.Restarts, p.GetStatus())
+	}
+	_ = p.Stop()
+}
+
+func TestRestartPolicyBackoff(t *testing.T) {
+	policy := RestartPolicy{Mode: RestartAlways, BackoffBase: Duration(time.Second), BackoffCap: Duration(5 * time.Second)}
+	if err := policy.Validate(); err != nil {
+		t.Fatalf("valid policy rejected: %v", err)
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, expected := range want {
+		if got := policy.backoff(i + 1); got != expected {
+			t.Errorf("attempt %d: expected %s, got %s", i+1, expected, got)
+		}
+	}
+
+	if *policy.MaxRetries != DefaultMaxRetries {
+		t.Errorf("expected an absent max_retries to default to %d, got %d", DefaultMaxRetries, *policy.MaxRetries)
+	}
+
+	// Zero retries is kept, also once saved and loaded again.
+	var strict RestartPolicy
+	if err := json.Unmarshal([]byte(`{"mode":"always","max_retries":0}`), &strict); err != nil {
+		t.Fatalf("failed to decode policy: %v", err)
+	}
+	if err := strict.Validate(); err != nil {
+		t.Fatalf("valid policy rejected: %v", err)
+	}
+	data, _ := json.Marshal(strict)
+	var reloaded RestartPolicy
+	if err := json.Unmarshal(data, &reloaded); err != nil || reloaded.Validate() != nil || *reloaded.MaxRetries != 0 {
+		t.Errorf("expected max_retries 0 to survive a round trip, got %s", data)
+	}
+
+	bad := RestartPolicy{Mode: "sometimes"}
+	if err := bad.Validate(); err == nil {
+		t.Error("invalid restart mode was accepted")
+	}
+}
+
+func TestStopGracefulAndEscalation(t *testing.T) {
+	pm := setupTestManager(t)
+
+	graceful, _ := pm.AddProcess("graceful", "/bin/sh", 0, WithStopSignal("term"))
+	if graceful.StopSignal != "SIGTERM" {
+		t.Errorf("expected canonical signal name 'SIGTERM', got '%s'", graceful.StopSignal)
+	}
+	if err := graceful.Start("-c", `trap "exit 0" TERM; while true; do sleep 0.05; done`); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond) // Let the shell install its trap
+	if err := graceful.Stop(); err != nil {
+		t.Fatalf("failed to stop process: %v", err)
+	}
+	if graceful.ExitCode != 0 || graceful.ExitSignal != "" {
+		t.Errorf("expected the trap to exit cleanly, got code=%d signal=%s", graceful.ExitCode, graceful.ExitSignal)
+	}
+
+	stubborn, _ := pm.AddProcess("stubborn", "/bin/sh", 0, WithStopTimeout(200*time.Millisecond))
+	if err := stubborn.Start("-c", `trap "" TERM; while true; do sleep 0.05; done`); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	start := time.Now()
+	if err := stubborn.Stop(); err != nil {
+		t.Fatalf("failed to stop process: %v", err)
+	}
+	if stubborn.ExitSignal != "SIGKILL" {
+		t.Errorf("expected escalation to SIGKILL, got signal '%s'", stubborn.ExitSignal)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("process was killed before its stop timeout, after %s", elapsed)
+	}
+	if stubborn.Stat != StatStopped {
+		t.Errorf("a requested stop must not count as a crash, got stat=%d", stubborn.Stat)
+	}
+
+	if _, err := pm.AddProcess("bad-signal", "/bin/true", 0, WithStopSignal("SIGNOPE")); err == nil {
+		t.Error("unknown stop signal was accepted")
+	}
+	for _, signal := range []string{"0", "-9", "999", "SIGSTOP", "SIGCONT", "SIGWINCH", strconv.Itoa(int(syscall.SIGTSTP)), strconv.Itoa(int(syscall.SIGCHLD)), strconv.Itoa(int(syscall.SIGURG)), strconv.Itoa(int(syscall.SIGTTIN)), strconv.Itoa(int(syscall.SIGTTOU))} {
+		if _, err := pm.AddProcess("bad-signal", "/bin/true", 0, WithStopSignal(signal)); !errors.Is(err, ErrInvalidSpec) {
+			t.Errorf("stop signal %q was accepted, got %v", signal, err)
+		}
+	}
+	if _, _, err := ParseSignal("9"); err != nil {
+		t.Errorf("expected signal number 9 to be accepted, got %v", err)
+	}
+}
+
+func TestStopKillsWholeProcessTree(t *testing.T) {
+	pm := setupTestManager(t)
+	p, _ := pm.AddProcess("forker", "/bin/sh", 0, WithStopTimeout(time.Second))
+	if err := p.Start("-c", "sleep 30 & sleep 30 & wait"); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond) // Let the shell fork its workers
+
+	tree, err := p.Tree()
+	if err != nil {
+		t.Fatalf("failed to read process tree: %v", err)
+	}
+	if tree.Pid != p.Pid || len(tree.Children) != 2 {
+		t.Fatalf("expected the shell with 2 children, got %+v", tree)
+	}
+
+	pgid := p.Pid
+	if err := p.Stop(); err != nil {
+		t.Fatalf("failed to stop process: %v", err)
+	}
+	if groupAlive(pgid) {
+		t.Error("workers of the process group survived Stop")
+	}
+	if _, err := p.Tree(); err == nil {
+		t.Error("expected an error for the tree of a stopped process")
+	}
+}
+
+func TestParseProcStat(t *testing.T) {
+	stat, ok := parseProcStat(42, []byte("42 (my (odd) cmd) S 7 42 42 0 -1"))
+	if !ok {
+		t.Fatal("failed to parse stat line")
+	}
+	if stat.command != "my (odd) cmd" || stat.state != "S" || stat.ppid != 7 || stat.pgrp != 42 {
+		t.Errorf("unexpected parse result: %+v", stat)
+	}
+}
+
+func TestOutputCapture(t *testing.T) {
+	pm := setupTestManager(t)
+	p, _ := pm.AddProcess("chatty", "/bin/sh", 0)
+	if err := p.Start("-c", "echo to-out; echo to-err >&2"); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	waitForExit(t, p)
+
+	logPath := filepath.Join(p.LogDir(), OutputLogName)
+	var content string
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(content, "to-err") || !strings.Contains(content, "to-out") {
+		if time.Now().After(deadline) {
+			t.Fatalf("output was not captured, log contains: %q", content)
+		}
+		time.Sleep(10 * time.Millisecond)
+		data, _ := os.ReadFile(logPath)
+		content = string(data)
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) != 3 {
+			t.Fatalf("malformed log line: %q", line)
+		}
+		if _, err := time.Parse(outputTimestamp, fields[0]); err != nil {
+			t.Errorf("line does not start with a timestamp: %q", line)
+		}
+		want := map[string]string{"to-out": "stdout", "to-err": "stderr"}[fields[2]]
+		if fields[1] != want {
+			t.Errorf("line %q tagged %s, expected %s", line, fields[1], want)
+		}
+	}
+}
+
+func TestOutputLogRotation(t *testing.T) {
+	dir := t.TempDir()
+	out, err := newOutputLog(dir, config.OutputLogConfig{Compress: true, Retention: 2}, t.Errorf)
+	if err != nil {
+		t.Fatalf("failed to create output log: %v", err)
+	}
+	defer out.Close()
+	out.maxSize = 100 // A few lines per file
+
+	for i := 0; i < 20; i++ {
+		out.writeLine("stdout", []byte("some output that fills the file"))
+	}
+	out.archived.Wait()
+
+	rotated, err := RotatedLogs(dir)
+	if err != nil {
+		t.Fatalf("failed to list rotated logs: %v", err)
+	}
+	if len(rotated) != 2 {
+		t.Fatalf("expected retention to keep 2 rotated files, got %d: %v", len(rotated), rotated)
+	}
+	for _, path := range rotated {
+		if !strings.HasSuffix(path, ".log.gz") {
+			t.Errorf("rotated file was not compressed: %s", path)
+		}
+	}
+
+	// Age-based rotation starts a new file once the active one is too old.
+	out.maxSize = 1 << 20
+	out.maxAge = time.Millisecond
+	before, _ := os.ReadDir(dir)
+	time.Sleep(5 * time.Millisecond)
+	out.writeLine("stderr", []byte("late line"))
+	out.archived.Wait()
+	info, err := os.Stat(filepath.Join(dir, OutputLogName))
+	if err != nil {
+		t.Fatalf("active log missing after age rotation: %v", err)
+	}
+	if info.Size() > 60 {
+		t.Errorf("active log was not rotated by age, size %d", info.Size())
+	}
+	if after, _ := os.ReadDir(dir); len(after) != len(before) {
+		t.Errorf("retention not applied after age rotation: %d files before, %d after", len(before), len(after))
+	}
+
+	// Rotations within the same millisecond must not overwrite each other.
+	burstDir := t.TempDir()
+	burst, err := newOutputLog(burstDir, config.OutputLogConfig{Retention: 100}, t.Errorf)
+	if err != nil {
+		t.Fatalf("failed to create output log: %v", err)
+	}
+	defer burst.Close()
+	burst.maxSize = 1 // Every line gets its own file
+	for i := 0; i < 10; i++ {
+		burst.writeLine("stdout", []byte(strconv.Itoa(i)))
+	}
+	burst.archived.Wait()
+	rotated, err = RotatedLogs(burstDir)
+	if err != nil {
+		t.Fatalf("failed to list rotated logs: %v", err)
+	}
+	if len(rotated) != 9 {
+		t.Fatalf("expected 9 rotated files after a burst, got %d: %v", len(rotated), rotated)
+	}
+	for i, path := range rotated {
+		data, err := os.ReadFile(path)
+		if err != nil || !strings.HasSuffix(string(data), " stdout "+strconv.Itoa(i)+"\n") {
+			t.Errorf("rotated file %s out of order or unreadable: %q %v", path, data, err)
+		}
+	}
+}
+
+func TestProcessEnvironmentAndDir(t *testing.T) {
+	pm := setupTestManager(t)
+	t.Setenv("PM_INHERITED", "from-manager")
+
+	envFile := filepath.Join(t.TempDir(), "app.env")
+	content := "# settings\nexport A=from-file\nB=\"two words\\tquoted\"\nC='literal $X' \nD=plain # comment\n"
+	if err := os.WriteFile(envFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+	values, err := LoadEnvFile(envFile)
+	if err != nil {
+		t.Fatalf("failed to parse env file: %v", err)
+	}
+	if values["A"] != "from-file" || values["B"] != "two words\tquoted" || values["C"] != "literal $X" || values["D"] != "plain" {
+		t.Errorf("unexpected env file values: %q", values)
+	}
+
+	workDir := t.TempDir()
+	p, err := pm.AddProcess("env-proc", "/bin/sh", 0,
+		WithEnvFiles(envFile),
+		WithEnv(map[string]string{"A": "from-env"}),
+		WithDir(workDir),
+		WithInheritEnv(false),
+	)
+	if err != nil {
+		t.Fatalf("failed to add process: %v", err)
+	}
+	if err := p.Start("-c", `echo "$A|$D|$PM_INHERITED|$(pwd)"`); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	waitForExit(t, p)
+	time.Sleep(50 * time.Millisecond) // Let the output reach the log
+
+	lines, err := p.TailLogs(1, "stdout")
+	if err != nil || len(lines) != 1 {
+		t.Fatalf("failed to read output: %v %v", lines, err)
+	}
+	if want := "from-env|plain||" + workDir; lines[0].Text != want {
+		t.Errorf("expected %q, got %q", want, lines[0].Text)
+	}
+
+	if _, err := pm.AddProcess("bad-dir", "/bin/true", 0, WithDir(filepath.Join(workDir, "missing"))); err == nil {
+		t.Error("missing working directory was accepted")
+	}
+}
+
+func TestDefaultArgs(t *testing.T) {
+	pm := setupTestManager(t)
+	p, _ := pm.AddProcess("echoer", "/bin/echo", 0, WithArgs("base", "two words"))
+
+	cases := []struct {
+		opts StartOptions
+		want string
+	}{
+		{StartOptions{}, "base two words"},
+		{StartOptions{Args: []string{"other"}}, "other"},
+		{StartOptions{Args: []string{"extra"}, ArgsMode: ArgsAppend}, "base two words extra"},
+	}
+	for _, c := range cases {
+		if err := p.StartWith(c.opts); err != nil {
+			t.Fatalf("failed to start process: %v", err)
+		}
+		waitForExit(t, p)
+		time.Sleep(50 * time.Millisecond) // Let the output reach the log
+		lines, _ := p.TailLogs(1, "stdout")
+		if len(lines) != 1 || lines[0].Text != c.want {
+			t.Errorf("start with %+v: expected %q, got %+v", c.opts, c.want, lines)
+		}
+	}
+
+	if err := p.StartWith(StartOptions{ArgsMode: "prepend"}); err == nil {
+		t.Error("invalid args mode was accepted")
+	}
+}
+
+func TestRunAsUser(t *testing.T) {
+	pm := setupTestManager(t)
+
+	if _, err := pm.AddProcess("typo", "/bin/true", 0, WithUser("no-such-user-here")); err == nil {
+		t.Error("unknown user was accepted")
+	}
+	if _, err := pm.AddProcess("typo", "/bin/true", 0, WithSupplementaryGroups("no-such-group-here")); err == nil {
+		t.Error("unknown supplementary group was accepted")
+	}
+
+	if os.Getuid() != 0 {
+		t.Skip("dropping privileges requires running the tests as root")
+	}
+	p, err := pm.AddProcess("unprivileged", "/bin/sh", 0, WithUser("nobody"))
+	if err != nil {
+		t.Fatalf("failed to add process: %v", err)
+	}
+	if err := p.Start("-c", `echo "$(id -u) $(id -G) $USER"`); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	waitForExit(t, p)
+	time.Sleep(50 * time.Millisecond) // Let the output reach the log
+
+	lines, _ := p.TailLogs(1, "stdout")
+	if len(lines) != 1 || !strings.HasPrefix(lines[0].Text, "65534 ") || !strings.HasSuffix(lines[0].Text, " nobody") {
+		t.Errorf("expected the child to run as nobody, got %+v", lines)
+	}
+	if strings.Contains(" "+lines[0].Text+" ", " 0 ") {
+		t.Errorf("child kept root's groups: %s", lines[0].Text)
+	}
+}
+
+func TestParseCron(t *testing.T) {
+	valid := []string{"* * * * *", "*/15 9-17 * * MON-FRI", "0 0 1,15 * *", "30 */5 * * * *", "0 0 * JAN,jul sun", "@hourly", "@DAILY", "0 12 * * 7"}
+	for _, expr := range valid {
+		if _, err := parseCron(expr); err != nil {
+			t.Errorf("parseCron(%q) failed: %v", expr, err)
+		}
+	}
+	invalid := []string{"", "* * * *", "* * * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "@often", "x * * * *"}
+	for _, expr := range invalid {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q) should have failed", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	start := time.Date(2024, time.January, 31, 10, 7, 30, 0, time.UTC) // a Wednesday
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.January, 31, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.January, 31, 10, 15, 0, 0, time.UTC)},
+		{"*/20 * * * * *", time.Date(2024, time.January, 31, 10, 7, 40, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2024, time.February, 1, 9, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2024, time.February, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2024, time.February, 4, 12, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: the 1st of the month or any Friday.
+		{"0 0 1 * FRI", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 15 * FRI", time.Date(2024, time.February, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		c, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q) failed: %v", tt.expr, err)
+		}
+		got, ok := c.next(start, time.UTC, false, false)
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("next(%q) = %v, %v; want %v", tt.expr, got, ok, tt.want)
+		}
+	}
+
+	c, _ := parseCron("0 0 30 2 *")
+	if got, ok := c.next(start, time.UTC, false, false); ok {
+		t.Errorf("expected no occurrence for February 30th, got %v", got)
+	}
+}
+
+func TestTimingRules(t *testing.T) {
+	pm := setupTestManager(t)
+	if err := pm.CreateTimingRule("every-second", "* * * * * *"); err != nil {
+		t.Fatalf("failed to create cron rule: %v", err)
+	}
+	if err := pm.CreateTimingRule("once", "1700000000"); err != nil {
+		t.Fatalf("failed to create one-shot rule: %v", err)
+	}
+	if err := pm.CreateTimingRule("bad", "every tuesday"); err == nil {
+		t.Error("expected an invalid schedule to be rejected")
+	}
+	if err := pm.CreateTimingRule("once", "@daily"); err == nil {
+		t.Error("expected a duplicate rule name to be rejected")
+	}
+
+	p, err := pm.AddProcess("cronjob", "/bin/true", 1)
+	if err != nil {
+		t.Fatalf("failed to add process: %v", err)
+	}
+	if err := p.Start(); err == nil {
+		t.Error("expected manual start of a scheduled process to fail")
+	}
+	if err := p.SetJob("every-second"); err != nil {
+		t.Fatalf("failed to set job: %v", err)
+	}
+	if err := p.StartJob(); err != nil {
+		t.Fatalf("failed to start job: %v", err)
+	}
+	if err := p.StartJob(); err == nil {
+		t.Error("expected starting an armed job twice to fail")
+	}
+
+	// The job should fire, and keep re-arming itself afterwards.
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		pm.processMutex.Lock()
+		fired, rearmed := !p.StartTime.IsZero(), p.jobTimer != nil
+		pm.processMutex.Unlock()
+		if fired && rearmed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("scheduled job did not fire and re-arm (fired=%v, rearmed=%v)", fired, rearmed)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if err := pm.RemoveProcess("cronjob"); err != nil {
+		t.Fatalf("failed to remove process: %v", err)
+	}
+	if p.jobTimer != nil {
+		t.Error("removing the process should disarm its job")
+	}
+}
+
+func TestIntervalRules(t *testing.T) {
+	base := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	rule, err := parseTimingRule("every 5m")
+	if err != nil {
+		t.Fatalf("failed to parse fixed-rate rule: %v", err)
+	}
+	rule.CreatedAt = base
+	if next, _ := rule.Next(base.Add(7 * time.Minute)); !next.Equal(base.Add(10 * time.Minute)) {
+		t.Errorf("expected fixed-rate occurrences aligned to the creation time, got %v", next)
+	}
+	if next, _ := rule.Next(base.Add(10 * time.Minute)); !next.Equal(base.Add(15 * time.Minute)) {
+		t.Errorf("expected the occurrence after 12:10 to be 12:15, got %v", next)
+	}
+
+	start, end := base.Add(time.Hour), base.Add(90*time.Minute)
+	if err := WithWindow(start, end)(&rule); err != nil {
+		t.Fatalf("failed to apply window: %v", err)
+	}
+	if next, _ := rule.Next(base); !next.Equal(start) {
+		t.Errorf("expected the first occurrence at the window start, got %v", next)
+	}
+	if next, ok := rule.Next(end); ok {
+		t.Errorf("expected no occurrence after the window end, got %v", next)
+	}
+
+	rule, err = parseTimingRule("30s after previous run finishes")
+	if err != nil {
+		t.Fatalf("failed to parse fixed-delay rule: %v", err)
+	}
+	if !rule.IsFixedDelay() || rule.String() != "30s after previous run finishes" {
+		t.Errorf("unexpected fixed-delay rule %q", rule.String())
+	}
+	if next, _ := rule.Next(base); !next.Equal(base.Add(30 * time.Second)) {
+		t.Errorf("expected the next run 30s after the previous one finished, got %v", next)
+	}
+
+	for _, input := range []string{"every 10ms", "every soon", "later after previous run finishes"} {
+		if _, err := parseTimingRule(input); err == nil {
+			t.Errorf("expected %q to be rejected", input)
+		}
+	}
+	once := TimingRule{ScheduleTime: base}
+	if err := WithWindow(start, end)(&once); err == nil {
+		t.Error("expected a window on a one-shot rule to be rejected")
+	}
+}
+
+func TestFixedDelayJob(t *testing.T) {
+	pm := setupTestManager(t)
+	if err := pm.CreateTimingRule("poll", "1s after previous run finishes"); err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	p, err := pm.AddProcess("poller", "sleep", 1, WithArgs("0.3"))
+	if err != nil {
+		t.Fatalf("failed to add process: %v", err)
+	}
+	if err := p.SetJob("poll"); err != nil {
+		t.Fatalf("failed to set job: %v", err)
+	}
+	if err := p.StartJob(); err != nil {
+		t.Fatalf("failed to start job: %v", err)
+	}
+
+	// The first run starts right away and nothing is armed while it runs.
+	time.Sleep(150 * time.Millisecond)
+	pm.processMutex.Lock()
+	running, armed := p.Stat == StatRunning, p.jobTimer != nil
+	pm.processMutex.Unlock()
+	if !running || armed {
+		t.Fatalf("expected the first run in progress with nothing armed (running=%v, armed=%v)", running, armed)
+	}
+
+	// Once it exits, the next run is armed relative to the exit time.
+	waitForExit(t, p)
+	time.Sleep(50 * time.Millisecond)
+	pm.processMutex.Lock()
+	delay := p.NextRun.Sub(p.EndTime)
+	armed = p.jobTimer != nil
+	pm.processMutex.Unlock()
+	if !armed || delay != time.Second {
+		t.Errorf("expected the next run armed 1s after the exit, got armed=%v delay=%v", armed, delay)
+	}
+
+	if err := pm.RemoveProcess("poller"); err != nil {
+		t.Fatalf("failed to remove process: %v", err)
+	}
+}
+
+// fakeClock is a Clock whose time only moves when Advance is called.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock   *fakeClock
+	at      time.Time
+	f       func()
+	stopped bool
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	timer := &fakeTimer{clock: c, at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, timer)
+	return timer
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	wasPending := !t.stopped
+	t.stopped = true
+	return wasPending
+}
+
+// Advance moves the clock forward by d, firing due timers in order.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	c.mu.Unlock()
+	for {
+		c.mu.Lock()
+		var due *fakeTimer
+		for _, timer := range c.timers {
+			if !timer.stopped && !timer.at.After(end) && (due == nil || timer.at.Before(due.at)) {
+				due = timer
+			}
+		}
+		if due == nil {
+			c.now = end
+			c.mu.Unlock()
+			return
+		}
+		due.stopped = true
+		c.now = due.at
+		c.mu.Unlock()
+		due.f()
+	}
+}
+
+func TestCronTimeZoneAndDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+
+	rule := TimingRule{Cron: "0 9 * * *"}
+	if err := WithTimeZone("Asia/Tokyo")(&rule); err != nil {
+		t.Fatalf("failed to set time zone: %v", err)
+	}
+	from := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC) // 09:00 in Tokyo
+	if next, _ := rule.Next(from); !next.Equal(time.Date(2024, time.June, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected 09:00 Tokyo time, got %v", next)
+	}
+	if err := WithTimeZone("Mars/Olympus_Mons")(&rule); err == nil {
+		t.Error("expected an unknown time zone to be rejected")
+	}
+	if err := WithDSTPolicy("later", "")(&rule); err == nil {
+		t.Error("expected an unknown DST gap policy to be rejected")
+	}
+
+	// On 2024-03-10 New York skips from 02:00 to 03:00.
+	beforeGap := time.Date(2024, time.March, 10, 0, 0, 0, 0, ny)
+	gap := TimingRule{Cron: "30 2 * * *", TimeZone: "America/New_York"}
+	if next, _ := gap.Next(beforeGap); !next.Equal(time.Date(2024, time.March, 10, 3, 0, 0, 0, ny)) {
+		t.Errorf("expected a skipped 02:30 to run when the gap ends, got %v", next)
+	}
+	gap.DSTGap = DSTGapSkip
+	if next, _ := gap.Next(beforeGap); !next.Equal(time.Date(2024, time.March, 11, 2, 30, 0, 0, ny)) {
+		t.Errorf("expected a skipped 02:30 to be skipped, got %v", next)
+	}
+
+	// On 2024-11-03 New York repeats 01:00 to 02:00.
+	beforeOverlap := time.Date(2024, time.November, 3, 0, 0, 0, 0, ny)
+	firstRun := time.Date(2024, time.November, 3, 5, 30, 0, 0, time.UTC)  // 01:30 EDT
+	secondRun := time.Date(2024, time.November, 3, 6, 30, 0, 0, time.UTC) // 01:30 EST
+	overlap := TimingRule{Cron: "30 1 * * *", TimeZone: "America/New_York"}
+	if next, _ := overlap.Next(beforeOverlap); !next.Equal(firstRun) {
+		t.Errorf("expected the first 01:30 to run, got %v", next)
+	}
+	if next, _ := overlap.Next(firstRun); !next.Equal(time.Date(2024, time.November, 4, 1, 30, 0, 0, ny)) {
+		t.Errorf("expected the repeated 01:30 to be skipped, got %v", next)
+	}
+	overlap.DSTOverlap = DSTOverlapBoth
+	if next, _ := overlap.Next(firstRun); !next.Equal(secondRun) {
+		t.Errorf("expected the repeated 01:30 to run again, got %v", next)
+	}
+}
+
+func TestJobUsesInjectedClock(t *testing.T) {
+	pm := setupTestManager(t)
+	clock := &fakeClock{now: time.Date(2024, time.March, 9, 12, 0, 0, 0, time.UTC)}
+	pm.clock = clock
+
+	if err := pm.CreateTimingRule("nightly", "30 2 * * *", WithTimeZone("America/New_York"), WithDSTPolicy(DSTGapSkip, "")); err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	p, err := pm.AddProcess("nightly", "/bin/true", 1)
+	if err != nil {
+		t.Fatalf("failed to add process: %v", err)
+	}
+	if err := p.SetJob("nightly"); err != nil {
+		t.Fatalf("failed to set job: %v", err)
+	}
+	if err := p.StartJob(); err != nil {
+		t.Fatalf("failed to start job: %v", err)
+	}
+
+	// 2024-03-10 02:30 does not exist in New York, so the job skips a day.
+	want := time.Date(2024, time.March, 11, 6, 30, 0, 0, time.UTC)
+	if !p.NextRun.Equal(want) {
+		t.Fatalf("expected the job armed for %v, got %v", want, p.NextRun)
+	}
+	clock.Advance(want.Sub(clock.Now()) - time.Second)
+	if !p.StartTime.IsZero() {
+		t.Fatal("job fired before its time")
+	}
+	clock.Advance(time.Second)
+	waitForExit(t, p)
+	if p.StartTime.IsZero() {
+		t.Fatal("job did not fire at its time")
+	}
+	pm.processMutex.Lock()
+	next := p.NextRun
+	pm.processMutex.Unlock()
+	if !next.Equal(want.AddDate(0, 0, 1)) {
+		t.Errorf("expected the job re-armed for the next day, got %v", next)
+	}
+
+	// Assigning another rule to the armed job re-arms it on that rule.
+	if err := pm.CreateTimingRule("hourly", "0 * * * *"); err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	if err := p.SetJob("hourly"); err != nil {
+		t.Fatalf("failed to replace job: %v", err)
+	}
+	pm.processMutex.Lock()
+	next = p.NextRun
+	pm.processMutex.Unlock()
+	if hour := clock.Now().Truncate(time.Hour).Add(time.Hour); !next.Equal(hour) {
+		t.Errorf("expected the job re-armed for %v on the new rule, got %v", hour, next)
+	}
+	if err := pm.RemoveProcess("nightly"); err != nil {
+		t.Fatalf("failed to remove process: %v", err)
+	}
+}
+
+func TestSchedulerResumesJobs(t *testing.T) {
+	pm := setupTestManager(t)
+	start := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	pm.clock = &fakeClock{now: start}
+
+	policies := map[string]string{"catchup": MisfireRunAll, "skipper": MisfireSkip, "once": MisfireRunOnce}
+	for name, policy := range policies {
+		if err := pm.CreateTimingRule(name, "every 1m", WithMisfirePolicy(policy)); err != nil {
+			t.Fatalf("failed to create rule: %v", err)
+		}
+		p, err := pm.AddProcess(name, "/bin/true", 1)
+		if err != nil {
+			t.Fatalf("failed to add process: %v", err)
+		}
+		if err := p.SetJob(name); err != nil {
+			t.Fatalf("failed to set job: %v", err)
+		}
+		if err := p.StartJob(); err != nil {
+			t.Fatalf("failed to start job: %v", err)
+		}
+	}
+	if err := pm.CreateTimingRule("bad", "every 1m", WithMisfirePolicy("sometimes")); err == nil {
+		t.Error("expected an unknown misfire policy to be rejected")
+	}
+	NewScheduler(pm).Stop() // The daemon shuts down before 12:01
+
+	// It comes back at 12:03:30, having missed 12:01, 12:02 and 12:03.
+	restarted := NewProcessManager(pm.logger, pm.config)
+	clock := &fakeClock{now: start.Add(3*time.Minute + 30*time.Second)}
+	restarted.clock = clock
+	if err := restarted.LoadProcessesFromDisk(); err != nil {
+		t.Fatalf("failed to load processes: %v", err)
+	}
+	NewScheduler(restarted).Start()
+
+	nextRegular := start.Add(4 * time.Minute)
+	skipper, _ := restarted.GetProcessByName("skipper")
+	if !skipper.NextRun.Equal(nextRegular) {
+		t.Errorf("expected skipped occurrences to wait for %v, got %v", nextRegular, skipper.NextRun)
+	}
+	once, _ := restarted.GetProcessByName("once")
+	catchup, _ := restarted.GetProcessByName("catchup")
+	if !once.NextRun.Equal(clock.Now()) || !catchup.NextRun.Equal(clock.Now()) {
+		t.Errorf("expected missed occurrences to run right away, got %v and %v", once.NextRun, catchup.NextRun)
+	}
+	if catchup.jobBacklog != 2 || once.jobBacklog != 0 {
+		t.Errorf("expected 2 missed runs owed for run_all and none for run_once, got %d and %d", catchup.jobBacklog, once.jobBacklog)
+	}
+
+	// Firing the catch-up run works through the backlog one run at a time.
+	clock.Advance(0)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		restarted.processMutex.Lock()
+		done := catchup.jobBacklog == 0 && catchup.Stat != StatRunning
+		restarted.processMutex.Unlock()
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("missed occurrences were not worked through")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	restarted.processMutex.Lock()
+	next, active := catchup.NextRun, catchup.JobActive
+	restarted.processMutex.Unlock()
+	if !next.Equal(nextRegular) || !active {
+		t.Errorf("expected the job to continue at %v, got %v (active=%v)", nextRegular, next, active)
+	}
+	NewScheduler(restarted).Stop()
+}
+
+func TestConcurrencyPolicies(t *testing.T) {
+	pm := setupTestManager(t)
+	clock := &fakeClock{now: time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)}
+	pm.clock = clock
+
+	runtimes := map[string]string{
+		ConcurrencyForbid:  "5",
+		ConcurrencyAllow:   "5",
+		ConcurrencyReplace: "5",
+		ConcurrencyQueue:   "0.5",
+	}
+	procs := map[string]*Process{}
+	for policy, seconds := range runtimes {
+		if err := pm.CreateTimingRule(policy, "every 1m", WithConcurrencyPolicy(policy)); err != nil {
+			t.Fatalf("failed to create rule: %v", err)
+		}
+		p, err := pm.AddProcess(policy, "sleep", 1, WithArgs(seconds))
+		if err != nil {
+			t.Fatalf("failed to add process: %v", err)
+		}
+		if err := p.SetJob(policy); err != nil {
+			t.Fatalf("failed to set job: %v", err)
+		}
+		if err := p.StartJob(); err != nil {
+			t.Fatalf("failed to start job: %v", err)
+		}
+		procs[policy] = p
+	}
+	if err := pm.CreateTimingRule("bad", "every 1m", WithConcurrencyPolicy("sometimes")); err == nil {
+		t.Error("expected an unknown concurrency policy to be rejected")
+	}
+	defer func() {
+		for policy := range procs {
+			_ = pm.RemoveProcess(policy)
+		}
+	}()
+
+	clock.Advance(time.Minute) // First runs start
+	firstPids := map[string]int{}
+	for policy, p := range procs {
+		if !p.IsRunning() {
+			t.Fatalf("%s: first run did not start", policy)
+		}
+		firstPids[policy] = p.Pid
+	}
+	clock.Advance(time.Minute) // The next occurrence overlaps
+
+	lastEvent := func(p *Process) string {
+		entries, err := p.History()
+		if err != nil {
+			t.Fatalf("%s: failed to read history: %v", p.Name, err)
+		}
+		for i := len(entries) - 1; i >= 0; i-- {
+			if event := entries[i].Event; event != EventRunStarted && event != EventRunFinished {
+				return event
+			}
+		}
+		t.Fatalf("%s: no job decision recorded", p.Name)
+		return ""
+	}
+
+	if got := lastEvent(procs[ConcurrencyForbid]); got != EventSkipped {
+		t.Errorf("forbid: expected a skipped entry, got %q", got)
+	}
+
+	allow := procs[ConcurrencyAllow]
+	pm.processMutex.Lock()
+	instances := len(allow.instances)
+	pm.processMutex.Unlock()
+	if instances != 1 || !allow.IsRunning() || lastEvent(allow) != EventAllowed {
+		t.Errorf("allow: expected a second instance next to the running one, got %d instance(s)", instances)
+	}
+
+	if got := lastEvent(procs[ConcurrencyQueue]); got != EventQueued {
+		t.Errorf("queue: expected a queued entry, got %q", got)
+	}
+
+	replace := procs[ConcurrencyReplace]
+	if got := lastEvent(replace); got != EventReplaced {
+		t.Errorf("replace: expected a replaced entry, got %q", got)
+	}
+
+	// The replacement run and the queued run both start without another tick.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		pm.processMutex.Lock()
+		replaced := replace.Stat == StatRunning && replace.Pid != firstPids[ConcurrencyReplace]
+		queue := procs[ConcurrencyQueue]
+		dequeued := queue.Stat == StatRunning && queue.Pid != firstPids[ConcurrencyQueue]
+		pm.processMutex.Unlock()
+		if replaced && dequeued {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected new runs for replace and queue (replaced=%v, dequeued=%v)", replaced, dequeued)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// Stopping the process also stops the extra instance.
+	if err := allow.Stop(); err != nil {
+		t.Fatalf("failed to stop process: %v", err)
+	}
+	pm.processMutex.Lock()
+	instances = len(allow.instances)
+	pm.processMutex.Unlock()
+	if instances != 0 {
+		t.Errorf("expected extra instances to be stopped with the process, %d left", instances)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	pm := setupTestManager(t)
+	p, err := pm.AddProcess("batch", "sleep", 0, WithRestartPolicy(RestartPolicy{Mode: RestartAlways}))
+	if err != nil {
+		t.Fatalf("failed to add process: %v", err)
+	}
+	if err := p.StartWith(StartOptions{Args: []string{"5"}, Timeout: 200 * time.Millisecond}); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	waitForExit(t, p)
+
+	if p.ExitReason != ExitReasonTimedOut || p.Stat != StatStopped {
+		t.Errorf("expected a stopped run recorded as timed out, got status %d and reason %q", p.Stat, p.ExitReason)
+	}
+	if p.ExitSignal != "SIGTERM" {
+		t.Errorf("expected the run to be stopped with its stop signal, got %q", p.ExitSignal)
+	}
+	if status := p.GetStatus(); status != "stopped" {
+		t.Errorf("a timed out run should not be restarted, got status %q", status)
+	}
+	entries, err := p.History()
+	if err != nil || !slices.ContainsFunc(entries, func(e HistoryEntry) bool { return e.Event == EventTimedOut }) {
+		t.Errorf("expected a timed_out history entry, got %v (err=%v)", entries, err)
+	}
+
+	// A run that ends in time is not affected.
+	if err := p.StartWith(StartOptions{Args: []string{"0.1"}, Timeout: time.Second}); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	waitForExit(t, p)
+	if p.ExitReason != "" {
+		t.Errorf("expected no exit reason for a run that finished in time, got %q", p.ExitReason)
+	}
+	if err := pm.RemoveProcess("batch"); err != nil {
+		t.Fatalf("failed to remove process: %v", err)
+	}
+}
+
+func TestRunTimeoutStopsOnlyItsInstance(t *testing.T) {
+	pm := setupTestManager(t)
+	clock := &fakeClock{now: time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)}
+	pm.clock = clock
+	if err := pm.CreateTimingRule("overlapping", "every 1m", WithConcurrencyPolicy(ConcurrencyAllow), WithTimeout(90*time.Second)); err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	p, err := pm.AddProcess("overlapping", "sleep", 1, WithArgs("30"))
+	if err != nil {
+		t.Fatalf("failed to add process: %v", err)
+	}
+	defer pm.RemoveProcess("overlapping")
+	if err := p.SetJob("overlapping"); err != nil {
+		t.Fatalf("failed to set job: %v", err)
+	}
+	if err := p.StartJob(); err != nil {
+		t.Fatalf("failed to start job: %v", err)
+	}
+
+	clock.Advance(time.Minute) // The tracked run starts
+	clock.Advance(time.Minute) // An instance starts next to it
+	pm.processMutex.Lock()
+	var instancePid int
+	for pid := range p.instances {
+		instancePid = pid
+	}
+	pm.processMutex.Unlock()
+	if instancePid == 0 || !p.IsRunning() {
+		t.Fatal("expected a tracked run and an extra instance")
+	}
+
+	clock.Advance(30 * time.Second) // Only the tracked run is past its timeout
+	waitForExit(t, p)
+	if p.Snapshot().ExitReason != ExitReasonTimedOut {
+		t.Errorf("expected the tracked run to time out, got reason %q", p.Snapshot().ExitReason)
+	}
+	pm.processMutex.Lock()
+	_, running := p.instances[instancePid]
+	pm.processMutex.Unlock()
+	if !running || syscall.Kill(instancePid, 0) != nil {
+		t.Error("the timeout of the tracked run also stopped the extra instance")
+	}
+}
+
+func TestInstanceTimeoutFollowsJobRule(t *testing.T) {
+	pm := setupTestManager(t)
+	clock := &fakeClock{now: time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)}
+	pm.clock = clock
+	if err := pm.CreateTimingRule("instances", "every 1m", WithConcurrencyPolicy(ConcurrencyAllow), WithTimeout(10*time.Minute)); err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	p, err := pm.AddProcess("instances", "sleep", 1, WithArgs("30"))
+	if err != nil {
+		t.Fatalf("failed to add process: %v", err)
+	}
+	defer pm.RemoveProcess("instances")
+	if err := p.SetJob("instances"); err != nil {
+		t.Fatalf("failed to set job: %v", err)
+	}
+	if err := p.StartJob(); err != nil {
+		t.Fatalf("failed to start job: %v", err)
+	}
+
+	clock.Advance(time.Minute) // The tracked run starts with a 10m timeout
+	pm.processMutex.Lock()
+	p.Timing.Timeout = Duration(20 * time.Second)
+	pm.processMutex.Unlock()
+
+	nextInstance := func() *instance {
+		t.Helper()
+		clock.Advance(time.Minute)
+		pm.processMutex.Lock()
+		defer pm.processMutex.Unlock()
+		if len(p.instances) != 1 {
+			t.Fatalf("expected one extra instance, got %d", len(p.instances))
+		}
+		for _, inst := range p.instances {
+			return inst
+		}
+		return nil
+	}
+	waitDone := func(inst *instance) {
+		t.Helper()
+		select {
+		case <-inst.done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("extra instance was still running after 5s")
+		}
+	}
+
+	// An instance that exits by itself disarms its timeout.
+	inst := nextInstance()
+	pm.processMutex.Lock()
+	timer, _ := inst.timer.(*fakeTimer)
+	pm.processMutex.Unlock()
+	if timer == nil {
+		t.Fatal("expected the extra instance to have a run timeout")
+	}
+	syscall.Kill(inst.cmd.Process.Pid, syscall.SIGKILL)
+	waitDone(inst)
+	if timer.Stop() {
+		t.Error("the timeout of an exited instance was left armed")
+	}
+
+	// Instances get the timeout of the job, not that of the tracked run.
+	inst = nextInstance()
+	clock.Advance(20 * time.Second)
+	waitDone(inst)
+	if !inst.timedOut {
+		t.Error("expected the extra instance to time out after the job's timeout")
+	}
+	if !p.IsRunning() {
+		t.Error("the tracked run should keep its own timeout")
+	}
+
+	// Instances are timed by the manager's clock, like the job itself.
+	runs, err := p.Runs()
+	if err != nil {
+		t.Fatalf("failed to read runs: %v", err)
+	}
+	want := time.Date(2024, time.May, 1, 12, 2, 0, 0, time.UTC)
+	if !slices.ContainsFunc(runs, func(r Run) bool { return r.StartTime.Equal(want) }) {
+		t.Errorf("expected an instance started at %v, got %+v", want, runs)
+	}
+}
+
+func TestRunHistory(t *testing.T) {
+	pm := setupTestManager(t)
+	retries := 1
+	policy := RestartPolicy{Mode: RestartOnFailure, MaxRetries: &retries, BackoffBase: Duration(10 * time.Millisecond)}
+	p, err := pm.AddProcess("history", "/bin/sh", 0, WithRestartPolicy(policy))
+	if err != nil {
+		t.Fatalf("failed to add process: %v", err)
+	}
+	if err := p.Start("-c", "echo failing; exit 3"); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+
+	// The failed run is restarted once before the process turns fatal.
+	deadline := time.Now().Add(5 * time.Second)
+	for p.GetStatus() != "fatal" {
+		if time.Now().After(deadline) {
+			t.Fatalf("process did not turn fatal, status %q", p.GetStatus())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	runs, err := p.Runs()
+	if err != nil {
+		t.Fatalf("failed to read runs: %v", err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("expected 2 runs, got %d: %+v", len(runs), runs)
+	}
+	if runs[0].Trigger != TriggerManual || runs[1].Trigger != TriggerRestart {
+		t.Errorf("expected a manual run and a restart, got %q and %q", runs[0].Trigger, runs[1].Trigger)
+	}
+	first := runs[0]
+	if first.ID == "" || first.ID == runs[1].ID {
+		t.Errorf("expected distinct run IDs, got %q and %q", first.ID, runs[1].ID)
+	}
+	if first.Status != RunFailed || first.ExitCode == nil || *first.ExitCode != 3 || first.EndTime == nil {
+		t.Errorf("expected a finished failed run with exit code 3, got %+v", first)
+	}
+	if !strings.HasPrefix(first.Logs, "/v1/processes/history/logs?since=") {
+		t.Errorf("unexpected logs link %q", first.Logs)
+	}
+
+	link, _ := url.Parse(first.Logs)
+	since, _ := time.Parse(time.RFC3339Nano, link.Query().Get("since"))
+	until, _ := time.Parse(time.RFC3339Nano, link.Query().Get("until"))
+	lines, err := p.LogsBetween(since, until, -1, "")
+	if err != nil || len(lines) != 1 || lines[0].Text != "failing" {
+		t.Errorf("expected the output of the first run only, got %v (err=%v)", lines, err)
+	}
+
+	// A run stopped on request is not a failure, even though it died of a signal.
+	stopped, _ := pm.AddProcess("stopped", "sleep", 0)
+	if err := stopped.Start("5"); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	if err := stopped.Stop(); err != nil {
+		t.Fatalf("failed to stop process: %v", err)
+	}
+	runs, _ = stopped.Runs()
+	if len(runs) != 1 || runs[0].Status != RunStopped || runs[0].ExitReason != ExitReasonStopped || runs[0].ExitSignal == "" {
+		t.Errorf("expected a stopped run ended by a signal, got %+v", runs)
+	}
+
+	// A run the daemon lost track of is closed when the state is loaded again.
+	sleeper, _ := pm.AddProcess("lost", "sleep", 0)
+	if err := sleeper.Start("5"); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	defer sleeper.Stop()
+	restarted := NewProcessManager(pm.logger, pm.config)
+	if err := restarted.LoadProcessesFromDisk(); err != nil {
+		t.Fatalf("failed to load processes: %v", err)
+	}
+	lost, _ := restarted.GetProcessByName("lost")
+	runs, _ = lost.Runs()
+	if len(runs) != 1 || runs[0].ExitReason != ExitReasonDaemonRestarted || runs[0].Status != RunFailed {
+		t.Errorf("expected the lost run to be closed as daemon_restarted, got %+v", runs)
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	policy := RetryPolicy{Backoff: BackoffExponential, Delay: Duration(time.Second), MaxDelay: Duration(5 * time.Second)}
+	if err := policy.Validate(); err != nil {
+		t.Fatalf("failed to validate retry policy: %v", err)
+	}
+	for retry, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second} {
+		if got := policy.delay(retry); got != want {
+			t.Errorf("retry %d: expected a delay of %v, got %v", retry, want, got)
+		}
+	}
+	if err := (&RetryPolicy{Backoff: "linear"}).Validate(); err == nil {
+		t.Error("expected an unknown backoff to be rejected")
+	}
+
+	pm := setupTestManager(t)
+	clock := &fakeClock{now: time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)}
+	pm.clock = clock
+
+	retry := WithRetryPolicy(RetryPolicy{MaxRetries: 2, Delay: Duration(10 * time.Second)})
+	if err := pm.CreateTimingRule("flaky", "every 1h", retry); err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	// The restart policy does not apply to runs the job retries.
+	p, err := pm.AddProcess("flaky", "/bin/sh", 1, WithArgs("-c", "exit 1"), WithRestartPolicy(RestartPolicy{Mode: RestartAlways}))
+	if err != nil {
+		t.Fatalf("failed to add process: %v", err)
+	}
+	defer pm.RemoveProcess("flaky")
+	if err := p.SetJob("flaky"); err != nil {
+		t.Fatalf("failed to set job: %v", err)
+	}
+	if err := p.StartJob(); err != nil {
+		t.Fatalf("failed to start job: %v", err)
+	}
+
+	clock.Advance(time.Hour) // First attempt
+	waitForExit(t, p)
+	for retry := 1; retry <= 2; retry++ {
+		clock.Advance(9 * time.Second)
+		if runs, _ := p.Runs(); len(runs) != retry {
+			t.Fatalf("retry %d started before its delay, got %d runs", retry, len(runs))
+		}
+		clock.Advance(time.Second)
+		waitForExit(t, p)
+	}
+	clock.Advance(time.Minute) // No further attempts
+
+	runs, err := p.Runs()
+	if err != nil {
+		t.Fatalf("failed to read runs: %v", err)
+	}
+	if len(runs) != 3 {
+		t.Fatalf("expected 3 attempts, got %d: %+v", len(runs), runs)
+	}
+	for i, run := range runs {
+		if run.Occurrence == "" || run.Occurrence != runs[0].Occurrence || run.Attempt != i+1 || run.Trigger != TriggerSchedule {
+			t.Errorf("expected attempt %d of occurrence %q, got %+v", i+1, runs[0].Occurrence, run)
+		}
+	}
+	entries, _ := p.History()
+	if last := entries[len(entries)-1]; last.Event != EventFailed || last.Occurrence != runs[0].Occurrence || last.Attempt != 3 {
+		t.Errorf("expected the occurrence to be marked failed after 3 attempts, got %+v", last)
+	}
+	if status := p.GetStatus(); status != "crashed" {
+		t.Errorf("expected the last attempt to stay crashed, got status %q", status)
+	}
+
+	// A pending retry counts against the concurrency policy: under the
+	// default policy the next occurrence is skipped in its favour.
+	retry = WithRetryPolicy(RetryPolicy{MaxRetries: 1, Delay: Duration(time.Minute)})
+	if err := pm.CreateTimingRule("busy", "every 1m", retry); err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	busy, _ := pm.AddProcess("busy", "/bin/sh", 1, WithArgs("-c", "exit 1"))
+	defer pm.RemoveProcess("busy")
+	if err := busy.SetJob("busy"); err != nil {
+		t.Fatalf("failed to set job: %v", err)
+	}
+	if err := busy.StartJob(); err != nil {
+		t.Fatalf("failed to start job: %v", err)
+	}
+	clock.Advance(time.Minute)
+	waitForExit(t, busy)
+	clock.Advance(time.Minute) // The next occurrence and the retry are due together
+	waitForExit(t, busy)
+
+	entries, _ = busy.History()
+	if !slices.ContainsFunc(entries, func(e HistoryEntry) bool { return e.Event == EventSkipped }) {
+		t.Errorf("expected the next occurrence to be skipped, got %+v", entries)
+	}
+	runs, _ = busy.Runs()
+	if len(runs) != 2 || runs[1].Attempt != 2 || runs[1].Occurrence != runs[0].Occurrence {
+		t.Errorf("expected the retry to run instead of the next occurrence, got %+v", runs)
+	}
+}
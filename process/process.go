@@ -2,28 +2,88 @@ package process
 
 import (
 	"ExeProcessManager/config"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"sync"
+	"syscall"
 	"time"
 )
 
+// Values stored in Process.Stat.
+const (
+	StatStopped = 0 // not running, either never started or stopped on request
+	StatRunning = 1 // running under the manager
+	StatCrashed = 2 // exited on its own with a non-zero code or a signal
+	StatFatal   = 3 // crashed too often in a row and will not be restarted
+)
+
 // Process struct defines a manageable process.
 type Process struct {
 	Pid     int    `json:"pid"`
 	Name    string `json:"name"`
 	Path    string `json:"path"`
-	Stat    int    `json:"stat"`    // 0: stopped, 1: running
+	Stat    int    `json:"stat"`    // see the Stat* constants
 	Schedul int    `json:"schedul"` // 0: manual, 1: automatic
 
+	Labels map[string]string `json:"labels,omitempty"` // for selecting processes, see MatchLabels
+
+	// Execution environment of the child.
+	Args       []string          `json:"args,omitempty"`      // default arguments, see StartOptions.ArgsMode
+	Env        map[string]string `json:"env,omitempty"`       // set on top of everything else
+	EnvFiles   []string          `json:"env_files,omitempty"` // dotenv files, read at every start
+	Dir        string            `json:"dir,omitempty"`       // working directory, the manager's if empty
+	InheritEnv bool              `json:"inherit_env"`         // start from the manager's environment
+
+	// Identity of the child; names or numeric ids, empty keeps the manager's.
+	User                string   `json:"user,omitempty"`
+	Group               string   `json:"group,omitempty"`                // primary group, the user's if empty
+	SupplementaryGroups []string `json:"supplementary_groups,omitempty"` // the user's groups if empty
+
+	Limits *Limits `json:"limits,omitempty"` // resource and cgroup limits
+
+	// Outcome of the most recent run, recorded by the exit watcher.
+	RunID      string    `json:"run_id,omitempty"` // identifies the run in the history
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time"`
+	ExitCode   int       `json:"exit_code"`
+	ExitSignal string    `json:"exit_signal,omitempty"`
+	ExitReason string    `json:"exit_reason,omitempty"` // e.g. ExitReasonOOMKilled
+
+	Restart  *RestartPolicy `json:"restart,omitempty"` // nil means never restart
+	Restarts int            `json:"restarts"`          // consecutive automatic restarts
+
+	StopSignal  string   `json:"stop_signal,omitempty"`  // sent by Stop, SIGTERM if empty
+	StopTimeout Duration `json:"stop_timeout,omitempty"` // grace period before SIGKILL
+
 	// Non-exported fields
-	process      *exec.Cmd   `json:"-"` // The running command
-	Timing       *TimingRule `json:"timing,omitempty"`
-	IsJobDeleted int         `json:"is_job_deleted"`
-	manager      *ProcessManager `json:"-"` // Reference to the manager for config/logging
+	process         *exec.Cmd         `json:"-"` // The running command
+	args            []string          // Arguments of the last start, reused by restarts
+	done            chan struct{}     // Closed by the exit watcher once the run is reaped
+	stopping        bool              // Set by Stop so the watcher does not report a crash
+	restartTimer    *time.Timer       // Pending automatic restart, if any
+	output          *outputLog        // Captured stdout/stderr, shared by all runs
+	oomKillsBase    int64             // Cgroup OOM kill count when the current run started
+	Timing          *TimingRule       `json:"timing,omitempty"`
+	JobRule         string            `json:"job_rule,omitempty"` // name of the rule Timing was copied from
+	IsJobDeleted    int               `json:"is_job_deleted"`
+	NextRun         time.Time         `json:"next_run"`   // next armed occurrence of the job, zero if none
+	JobActive       bool              `json:"job_active"` // the job was started and has occurrences left
+	jobBacklog      int               // Missed or queued occurrences still to run, one after another
+	instances       map[int]*instance // Extra runs started by the allow concurrency policy, by PID
+	runTimeout      time.Duration     // Timeout of the current run, zero for none
+	timeoutTimer    Timer             // Pending run timeout, if any
+	timedOut        bool              // The current run is being stopped for exceeding its timeout
+	runOccurrence   string            // Scheduled occurrence the current run is an attempt of, if any
+	runAttempt      int               // Attempt of that occurrence, from 1
+	retry           *pendingRetry     // Next attempt of a failed occurrence, if any
+	jobTimer        Timer             // Pending job occurrence, if any
+	jobAwaitingExit bool              // A fixed-delay job waits for the current run to exit
+	manager         *ProcessManager   `json:"-"` // Reference to the manager for config/logging
 }
 
 // ProcessManager manages all processes.
@@ -32,6 +92,7 @@ type ProcessManager struct {
 	logger       *slog.Logger
 	config       *config.Config
 	processMutex sync.Mutex
+	clock        Clock // Time source of the scheduler
 }
 
 // NewProcessManager creates a new instance of ProcessManager.
@@ -40,34 +101,46 @@ func NewProcessManager(logger *slog.Logger, cfg *config.Config) *ProcessManager
 		Processes: make([]*Process, 0),
 		logger:    logger,
 		config:    cfg,
+		clock:     realClock{},
 	}
 }
 
 // NewProcess creates a new process instance.
 func (pm *ProcessManager) NewProcess(name, path string, schedul int) *Process {
 	return &Process{
-		Pid:     0,
-		Name:    name,
-		Path:    path,
-		Stat:    0,
-		Schedul: schedul,
-		manager: pm, // Link back to the manager
+		Pid:        0,
+		Name:       name,
+		Path:       path,
+		Stat:       0,
+		Schedul:    schedul,
+		InheritEnv: true,
+		manager:    pm, // Link back to the manager
 	}
 }
 
-// AddProcess creates a new process and adds it to the manager.
-func (pm *ProcessManager) AddProcess(name, path string, schedul int) (*Process, error) {
+// AddProcess creates a new process, applies the given options and adds it to the manager.
+func (pm *ProcessManager) AddProcess(name, path string, schedul int, opts ...ProcessOption) (*Process, error) {
+	// The name is part of the paths of the state file, the logs and the cgroup.
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+
 	pm.processMutex.Lock()
 	defer pm.processMutex.Unlock()
 
 	// Check for existing process with the same name
 	for _, p := range pm.Processes {
 		if p.Name == name {
-			return nil, fmt.Errorf("process with name '%s' already exists", name)
+			return nil, errorf(ErrAlreadyExists, "process with name '%s' already exists", name)
 		}
 	}
 
 	proc := pm.NewProcess(name, path, schedul)
+	for _, opt := range opts {
+		if err := opt(proc); err != nil {
+			return nil, errorf(ErrInvalidSpec, "invalid option for process '%s': %w", name, err)
+		}
+	}
 	if err := proc.SaveState(); err != nil {
 		return nil, fmt.Errorf("failed to save initial process state: %w", err)
 	}
@@ -77,119 +150,405 @@ func (pm *ProcessManager) AddProcess(name, path string, schedul int) (*Process,
 	return proc, nil
 }
 
-// Start starts a manually-controlled process.
+// Ways StartOptions.Args combine with the persisted Process.Args.
+const (
+	ArgsReplace = "replace" // start arguments, if any, are used instead of the defaults
+	ArgsAppend  = "append"  // start arguments are added after the defaults
+)
+
+// StartOptions holds per-start settings of a manual start.
+type StartOptions struct {
+	Args     []string
+	ArgsMode string        // ArgsReplace (default) or ArgsAppend
+	Timeout  time.Duration // stop the run once it has taken this long, if set
+	Trigger  string        // what started the run for its history, TriggerManual if empty
+
+	occurrence string // scheduled occurrence the run is an attempt of
+	attempt    int
+}
+
+// ValidateArgsMode checks an arguments mode, which may be empty for the default.
+func ValidateArgsMode(mode string) error {
+	switch mode {
+	case "", ArgsReplace, ArgsAppend:
+		return nil
+	default:
+		return errorf(ErrInvalidSpec, "invalid args mode '%s': must be replace or append", mode)
+	}
+}
+
+// effectiveArgs combines the persisted default arguments with start arguments.
+func (p *Process) effectiveArgs(args []string, mode string) []string {
+	switch {
+	case mode == ArgsAppend:
+		return append(append([]string{}, p.Args...), args...)
+	case len(args) > 0:
+		return args
+	default:
+		return p.Args
+	}
+}
+
+// Start starts a manually-controlled process. Arguments given here replace
+// the persisted default arguments; without any, the defaults are used.
 func (p *Process) Start(args ...string) error {
+	return p.StartWith(StartOptions{Args: args})
+}
+
+// StartWith starts a manually-controlled process with per-start options.
+func (p *Process) StartWith(opts StartOptions) error {
+	if err := ValidateArgsMode(opts.ArgsMode); err != nil {
+		return err
+	}
+
 	p.manager.processMutex.Lock()
 	defer p.manager.processMutex.Unlock()
 
 	if p.Schedul == 1 {
-		return fmt.Errorf("process '%s' is scheduled and cannot be started manually", p.Name)
+		return errorf(ErrScheduledOnly, "process '%s' is scheduled and cannot be started manually", p.Name)
 	}
-	if p.Stat == 1 {
-		return fmt.Errorf("process '%s' is already running with PID %d", p.Name, p.Pid)
+	return p.startLocked(opts)
+}
+
+// startLocked starts a run of the process, whether manual or scheduled.
+// The caller must hold the manager lock.
+func (p *Process) startLocked(opts StartOptions) error {
+	if p.Stat == StatRunning {
+		return errorf(ErrAlreadyRunning, "process '%s' is already running with PID %d", p.Name, p.Pid)
 	}
 
-	cmd := exec.Command(p.Path, args...)
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start process executable: %w", err)
+	// A new start overrides any pending restart and clears a fatal state.
+	p.cancelRestart()
+	p.Restarts = 0
+
+	p.runTimeout = opts.Timeout // Restarts of this run get the same timeout
+	p.runOccurrence, p.runAttempt = opts.occurrence, opts.attempt
+	trigger := opts.Trigger
+	if trigger == "" {
+		trigger = TriggerManual
+	}
+	if err := p.launch(p.effectiveArgs(opts.Args, opts.ArgsMode), trigger); err != nil {
+		return err
+	}
+
+	// Persist the new state
+	return p.SaveState()
+}
+
+// launch executes the command, records the run in the history and hands it
+// to an exit watcher. The caller must hold the manager lock.
+func (p *Process) launch(args []string, trigger string) error {
+	cmd, err := p.spawn(args)
+	if err != nil {
+		return err
 	}
 
 	p.process = cmd
+	p.args = args // Restarts repeat exactly what this run used
 	p.Pid = cmd.Process.Pid
-	p.Stat = 1 // Mark as running
+	p.Stat = StatRunning
+	p.StartTime = time.Now()
+	p.RunID = newRunID(p.StartTime)
+	p.EndTime = time.Time{}
+	p.ExitCode = 0
+	p.ExitSignal = ""
+	p.ExitReason = ""
+	p.stopping = false
+	p.timedOut = false
+	p.done = make(chan struct{})
+	if p.runTimeout > 0 {
+		p.armTimeout(cmd)
+	}
+
+	p.recordRunStart(HistoryEntry{Time: p.StartTime, RunID: p.RunID, Trigger: trigger, Pid: p.Pid, Occurrence: p.runOccurrence, Attempt: p.runAttempt})
+
+	// The watcher owns cmd.Wait from here on and updates the state when the child exits.
+	go p.watch(cmd, p.done)
 
 	p.manager.logger.Info("process started successfully", "name", p.Name, "pid", p.Pid)
+	return nil
+}
 
-	// Persist the new state
-	return p.SaveState()
+// spawn starts a child with the process's configuration: environment,
+// credentials, limits and output capture. The caller must hold the manager
+// lock and becomes responsible for waiting on the returned command.
+func (p *Process) spawn(args []string) (*exec.Cmd, error) {
+	env, err := p.buildEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build environment: %w", err)
+	}
+
+	cmd := exec.Command(p.Path, args...)
+	cmd.Env = env
+	cmd.Dir = p.Dir
+	// Each child leads its own process group so Stop can reach every worker it forks.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if cmd.SysProcAttr.Credential, err = p.credential(); err != nil {
+		return nil, fmt.Errorf("failed to resolve process credentials: %w", err)
+	}
+	releaseCgroup, err := p.prepareLimits(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply cgroup limits: %w", err)
+	}
+	defer releaseCgroup()
+	awaitRlimits, err := p.prepareRlimits(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply resource limits: %w", err)
+	}
+
+	out, err := p.outputLog()
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare output log: %w", err)
+	}
+	stdout, err := out.attach("stdout")
+	if err != nil {
+		return nil, err
+	}
+	defer stdout.Close() // The child holds its own copy once started
+	stderr, err := out.attach("stderr")
+	if err != nil {
+		return nil, err
+	}
+	defer stderr.Close()
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		_ = awaitRlimits()
+		return nil, fmt.Errorf("failed to start process executable: %w", err)
+	}
+	if err := awaitRlimits(); err != nil {
+		_ = cmd.Wait() // The helper exits after reporting
+		return nil, err
+	}
+	return cmd, nil
 }
 
-// Stop terminates the process.
+// Stop terminates the process using its configured stop signal and timeout.
 func (p *Process) Stop() error {
+	return p.StopWith("", 0)
+}
+
+// StopWith sends the given signal (the process default if empty) and waits
+// for the process to exit. If it is still running once the timeout (the
+// process default if zero) has passed, it is killed with SIGKILL. Extra
+// instances started by the allow concurrency policy are stopped as well.
+func (p *Process) StopWith(signal string, timeout time.Duration) error {
 	p.manager.processMutex.Lock()
-	defer p.manager.processMutex.Unlock()
 
-	if p.Stat == 0 {
-		return fmt.Errorf("process '%s' is not running", p.Name)
+	if p.Stat != StatRunning && len(p.instances) == 0 {
+		defer p.manager.processMutex.Unlock()
+		// Stopping a process that is waiting to be restarted cancels the restart.
+		if p.cancelRestart() {
+			p.manager.logger.Info("pending restart cancelled", "name", p.Name)
+			return nil
+		}
+		return errorf(ErrNotRunning, "process '%s' is not running", p.Name)
 	}
 
-	// On Windows, FindProcess is needed. On Unix, the process handle is sufficient.
-	osProc, err := os.FindProcess(p.Pid)
+	if signal == "" {
+		signal = p.StopSignal
+	}
+	if signal == "" {
+		signal = DefaultStopSignal
+	}
+	sig, sigName, err := parseStopSignal(signal)
 	if err != nil {
-		// If the process doesn't exist, it might have already terminated.
-		p.manager.logger.Warn("could not find process to stop, it may have already exited", "name", p.Name, "pid", p.Pid)
-		p.Stat = 0
-		p.Pid = 0
-		return p.SaveState()
+		p.manager.processMutex.Unlock()
+		return err
+	}
+	if timeout <= 0 {
+		timeout = time.Duration(p.StopTimeout)
+	}
+	if timeout <= 0 {
+		timeout = DefaultStopTimeout
 	}
 
-	if err := osProc.Kill(); err != nil {
-		return fmt.Errorf("failed to kill process: %w", err)
+	// Every run to stop is identified by its process group and the channel
+	// its watcher closes once the leader has exited.
+	groups := map[int]chan struct{}{}
+	if p.Stat == StatRunning {
+		p.stopping = true
+		if err := p.signal(sig); err != nil {
+			p.stopping = false
+			p.manager.processMutex.Unlock()
+			return fmt.Errorf("failed to send %s to process: %w", sigName, err)
+		}
+		groups[p.Pid] = p.done
+	} else {
+		// Only extra instances are left; stopping them also ends any restart.
+		p.cancelRestart()
+	}
+	for pid, inst := range p.instances {
+		inst.stopping = true
+		if err := syscall.Kill(-pid, sig); err != nil && !errors.Is(err, syscall.ESRCH) {
+			p.manager.logger.Error("failed to signal process instance", "name", p.Name, "pid", pid, "signal", sigName, "error", err)
+		}
+		groups[pid] = inst.done
 	}
 
-	// Wait for the process to release resources, optional but good practice
-	_, _ = p.process.Process.Wait()
+	// The watcher needs the lock to record the exit, so release it before waiting.
+	p.manager.processMutex.Unlock()
+
+	deadline := time.After(timeout)
+	var giveUp <-chan time.Time
+	for pid, done := range groups {
+		select {
+		case <-done:
+		case <-deadline:
+			p.manager.logger.Warn("process did not exit within stop timeout, killing it", "name", p.Name, "pid", pid, "signal", sigName, "timeout", timeout.String())
+			deadline = nil // Every remaining run is killed straight away
+			for pgid := range groups {
+				p.killGroup(pgid)
+			}
+			giveUp = time.After(time.Second)
+			<-done
+		}
+	}
 
-	p.manager.logger.Info("process stopped successfully", "name", p.Name, "pid", p.Pid)
+	// Workers may outlive the group leader; give them the rest of the grace
+	// period before killing whatever is left in the group.
+	for pid := range groups {
+	drain:
+		for groupAlive(pid) {
+			select {
+			case <-deadline:
+				p.manager.logger.Warn("processes left in group after stop timeout, killing them", "name", p.Name, "pgid", pid)
+				for pgid := range groups {
+					p.killGroup(pgid)
+				}
+				deadline = nil // Only escalate once
+				giveUp = time.After(time.Second)
+			case <-giveUp:
+				p.manager.logger.Error("processes in group survived SIGKILL", "name", p.Name, "pgid", pid)
+				break drain
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+	}
 
-	p.Stat = 0
-	p.Pid = 0
-	p.process = nil
+	for pid := range groups {
+		p.manager.logger.Info("process stopped successfully", "name", p.Name, "pid", pid, "signal", sigName)
+	}
+	return nil
+}
 
-	return p.SaveState()
+// signal delivers sig to the whole process group of the running child. A
+// group that has already exited is not an error; the watcher records the
+// exit. The caller must hold the manager lock.
+func (p *Process) signal(sig syscall.Signal) error {
+	if p.process == nil {
+		return nil
+	}
+	if err := syscall.Kill(-p.Pid, sig); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	return nil
+}
+
+// killGroup sends SIGKILL to every process in the group pgid.
+func (p *Process) killGroup(pgid int) {
+	if err := syscall.Kill(-pgid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		p.manager.logger.Error("failed to kill process group", "name", p.Name, "pgid", pgid, "error", err)
+	}
 }
 
 // GetStatus returns a human-readable status string.
 func (p *Process) GetStatus() string {
-	if p.Stat == 1 {
-		// We can also ping the process to be sure
-		proc, err := os.FindProcess(p.Pid)
-		if err != nil || proc == nil {
-			return "stopped (crashed)"
-		}
+	p.manager.processMutex.Lock()
+	defer p.manager.processMutex.Unlock()
+
+	var status string
+	switch p.Stat {
+	case StatRunning:
 		return "running"
+	case StatCrashed:
+		status = "crashed"
+	case StatFatal:
+		return "fatal"
+	default:
+		status = "stopped"
 	}
-	return "stopped"
+	if p.restartTimer != nil {
+		status += " (restarting)"
+	}
+	return status
+}
+
+// IsRunning reports whether the process currently has a live child.
+func (p *Process) IsRunning() bool {
+	p.manager.processMutex.Lock()
+	defer p.manager.processMutex.Unlock()
+	return p.Stat == StatRunning
 }
 
-// RemoveProcess finds a process by name and removes it from the manager.
+// RemoveProcess finds a process by name, stops it if needed and removes it from the manager.
 func (pm *ProcessManager) RemoveProcess(name string) error {
+	p, err := pm.GetProcessByName(name)
+	if err != nil {
+		return err
+	}
+
+	// Disarm the job first so no new run starts while the process is stopped.
+	pm.processMutex.Lock()
+	p.cancelJob()
+	active := p.Stat == StatRunning || len(p.instances) > 0
+	pm.processMutex.Unlock()
+
+	// Stop takes the manager lock itself, so it must run before we lock for removal.
+	if active {
+		if err := p.Stop(); err != nil {
+			pm.logger.Error("failed to stop process during removal, attempting to continue", "name", p.Name, "error", err)
+		}
+	}
+
 	pm.processMutex.Lock()
 	defer pm.processMutex.Unlock()
 
-	for i, p := range pm.Processes {
-		if p.Name == name {
-			if p.Stat == 1 {
-				// Use the official Stop method to ensure clean state change
-				if err := p.Stop(); err != nil {
-					pm.logger.Error("failed to stop process during removal, attempting to continue", "name", p.Name, "error", err)
-				}
+	for i, q := range pm.Processes {
+		if q != p {
+			continue
+		}
+		p.cancelRestart()
+		if p.output != nil {
+			if err := p.output.Close(); err != nil {
+				pm.logger.Error("failed to close output log", "name", p.Name, "error", err)
 			}
+		}
+		if err := p.removeCgroup(); err != nil {
+			pm.logger.Error("failed to remove cgroup", "name", p.Name, "error", err)
+		}
 
-			// Remove process state file
-			if err := p.DeleteStateFile(); err != nil {
-				pm.logger.Error("failed to delete process state file", "name", p.Name, "error", err)
-				// Continue with removal from memory regardless
-			}
-			
-			// Remove schedule file if it exists
-			if p.Schedul == 1 {
-				scheduleFilePath := filepath.Join(pm.config.ScheduleDir, p.Name+".json")
-				if FileExists(scheduleFilePath) {
-					if err := os.Remove(scheduleFilePath); err != nil {
-						pm.logger.Error("failed to delete schedule file", "name", p.Name, "path", scheduleFilePath, "error", err)
-					}
+		// Remove process state file
+		if err := p.DeleteStateFile(); err != nil {
+			pm.logger.Error("failed to delete process state file", "name", p.Name, "error", err)
+			// Continue with removal from memory regardless
+		}
+
+		// Remove schedule file if it exists
+		if p.Schedul == 1 {
+			scheduleFilePath := filepath.Join(pm.config.ScheduleDir, p.Name+".json")
+			if FileExists(scheduleFilePath) {
+				if err := os.Remove(scheduleFilePath); err != nil {
+					pm.logger.Error("failed to delete schedule file", "name", p.Name, "path", scheduleFilePath, "error", err)
 				}
 			}
-
-
-			// Remove from the slice
-			pm.Processes = append(pm.Processes[:i], pm.Processes[i+1:]...)
-			pm.logger.Info("process removed successfully", "name", name)
-			return nil
 		}
+
+		// Remove from the slice
+		pm.Processes = append(pm.Processes[:i], pm.Processes[i+1:]...)
+		pm.logger.Info("process removed successfully", "name", name)
+		return nil
 	}
-	return fmt.Errorf("process with name '%s' not found", name)
+	return errorf(ErrNotFound, "process with name '%s' not found", name)
+}
+
+// List returns the managed processes. The slice is a copy, safe to range
+// over while processes are added or removed.
+func (pm *ProcessManager) List() []*Process {
+	pm.processMutex.Lock()
+	defer pm.processMutex.Unlock()
+	return slices.Clone(pm.Processes)
 }
 
 // GetProcessByName finds and returns a process by its name.
@@ -202,7 +561,7 @@ func (pm *ProcessManager) GetProcessByName(name string) (*Process, error) {
 			return p, nil
 		}
 	}
-	return nil, fmt.Errorf("process with name '%s' not found", name)
+	return nil, errorf(ErrNotFound, "process with name '%s' not found", name)
 }
 
 // SaveState saves the process's current state to a JSON file.
@@ -219,15 +578,14 @@ func (p *Process) SaveState() error {
 
 // DeleteStateFile removes the process's state file.
 func (p *Process) DeleteStateFile() error {
-    dataDir := p.manager.config.DataDir
+	dataDir := p.manager.config.DataDir
 	stateFilePath := filepath.Join(dataDir, "processes", p.Name+".json")
 	if !FileExists(stateFilePath) {
-        return nil // Nothing to delete
-    }
+		return nil // Nothing to delete
+	}
 	return os.Remove(stateFilePath)
 }
 
-
 // LoadProcessesFromDisk scans the process data directory and loads all processes into the manager.
 func (pm *ProcessManager) LoadProcessesFromDisk() error {
 	pm.processMutex.Lock()
@@ -251,16 +609,23 @@ func (pm *ProcessManager) LoadProcessesFromDisk() error {
 		}
 
 		filePath := filepath.Join(processDir, file.Name())
-		proc := &Process{manager: pm} // Create new process with manager reference
+		// Defaults are set before decoding so state files written before a
+		// field existed keep the old behaviour.
+		proc := &Process{InheritEnv: true, manager: pm}
 
 		if err := LoadFromFile(filePath, proc); err != nil {
 			pm.logger.Warn("failed to load process state from file, skipping", "file", filePath, "error", err)
 			continue
 		}
-		
-		// Reset state on load - assume all processes are stopped initially.
-		// A more advanced system could check if the PID is still active.
-		proc.Stat = 0
+
+		// A run recorded as active belonged to a previous daemon and cannot be
+		// watched any more, so treat it as stopped. Crash records are kept.
+		if proc.Stat == StatRunning {
+			proc.Stat = StatStopped
+			if proc.RunID != "" {
+				proc.recordHistory(HistoryEntry{Event: EventRunFinished, RunID: proc.RunID, Pid: proc.Pid, ExitReason: ExitReasonDaemonRestarted})
+			}
+		}
 		proc.Pid = 0
 		proc.process = nil
 
@@ -272,4 +637,4 @@ func (pm *ProcessManager) LoadProcessesFromDisk() error {
 		pm.logger.Info("loaded processes from disk", "count", loadedCount)
 	}
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,141 @@
+package process
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"syscall"
+)
+
+// TreeNode is one process in the tree of a managed process and its descendants.
+type TreeNode struct {
+	Pid      int         `json:"pid"`
+	Command  string      `json:"command"`
+	State    string      `json:"state"`
+	Children []*TreeNode `json:"children,omitempty"`
+}
+
+// procStat holds the fields of /proc/<pid>/stat used by the manager.
+type procStat struct {
+	pid     int
+	command string
+	state   string
+	ppid    int
+	pgrp    int
+}
+
+// readProcStats parses the stat file of every process visible in /proc.
+func readProcStats() ([]procStat, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read /proc: %w", err)
+	}
+
+	stats := make([]procStat, 0, len(entries))
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue // Not a process directory
+		}
+		data, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "stat"))
+		if err != nil {
+			continue // The process exited while we were scanning
+		}
+		if stat, ok := parseProcStat(pid, data); ok {
+			stats = append(stats, stat)
+		}
+	}
+	return stats, nil
+}
+
+// parseProcStat parses "pid (comm) state ppid pgrp ...". The command may
+// contain spaces and parentheses, so it is delimited by the last ')'.
+func parseProcStat(pid int, data []byte) (procStat, bool) {
+	open := bytes.IndexByte(data, '(')
+	end := bytes.LastIndexByte(data, ')')
+	if open < 0 || end < open {
+		return procStat{}, false
+	}
+	fields := bytes.Fields(data[end+1:])
+	if len(fields) < 3 {
+		return procStat{}, false
+	}
+	ppid, err1 := strconv.Atoi(string(fields[1]))
+	pgrp, err2 := strconv.Atoi(string(fields[2]))
+	if err1 != nil || err2 != nil {
+		return procStat{}, false
+	}
+	return procStat{
+		pid:     pid,
+		command: string(data[open+1 : end]),
+		state:   string(fields[0]),
+		ppid:    ppid,
+		pgrp:    pgrp,
+	}, true
+}
+
+// buildTree returns the tree rooted at pid, or nil if pid is not in stats.
+func buildTree(pid int, stats []procStat) *TreeNode {
+	children := make(map[int][]procStat)
+	var root *procStat
+	for i := range stats {
+		children[stats[i].ppid] = append(children[stats[i].ppid], stats[i])
+		if stats[i].pid == pid {
+			root = &stats[i]
+		}
+	}
+	if root == nil {
+		return nil
+	}
+
+	var build func(stat procStat) *TreeNode
+	build = func(stat procStat) *TreeNode {
+		node := &TreeNode{Pid: stat.pid, Command: stat.command, State: stat.state}
+		kids := children[stat.pid]
+		sort.Slice(kids, func(i, j int) bool { return kids[i].pid < kids[j].pid })
+		for _, kid := range kids {
+			node.Children = append(node.Children, build(kid))
+		}
+		return node
+	}
+	return build(*root)
+}
+
+// Tree returns the running process and all of its descendants.
+func (p *Process) Tree() (*TreeNode, error) {
+	p.manager.processMutex.Lock()
+	pid, running := p.Pid, p.Stat == StatRunning
+	p.manager.processMutex.Unlock()
+
+	if !running {
+		return nil, errorf(ErrNotRunning, "process '%s' is not running", p.Name)
+	}
+
+	stats, err := readProcStats()
+	if err != nil {
+		return nil, err
+	}
+	tree := buildTree(pid, stats)
+	if tree == nil {
+		return nil, errorf(ErrNotRunning, "process '%s' (PID %d) not found in /proc", p.Name, pid)
+	}
+	return tree, nil
+}
+
+// groupAlive reports whether any live (non-zombie) process is left in the
+// process group pgid. Without /proc it falls back to probing with signal 0.
+func groupAlive(pgid int) bool {
+	stats, err := readProcStats()
+	if err != nil {
+		return syscall.Kill(-pgid, 0) == nil
+	}
+	for _, stat := range stats {
+		if stat.pgrp == pgid && stat.state != "Z" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package process
+
+import (
+	"fmt"
+	"time"
+)
+
+// Restart modes accepted by RestartPolicy.Mode.
+const (
+	RestartNever     = "never"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+// Defaults used when a RestartPolicy leaves a field at zero.
+const (
+	DefaultMaxRetries  = 5
+	DefaultBackoffBase = time.Second
+	DefaultBackoffCap  = time.Minute
+	DefaultStableAfter = 10 * time.Second
+)
+
+// RestartPolicy controls what the manager does when a process exits without
+// being asked to stop.
+type RestartPolicy struct {
+	Mode        string   `json:"mode"`                   // never, on-failure or always
+	MaxRetries  *int     `json:"max_retries,omitempty"`  // consecutive restarts before the process turns fatal, DefaultMaxRetries if absent
+	BackoffBase Duration `json:"backoff_base,omitempty"` // delay before the first restart, doubled each time
+	BackoffCap  Duration `json:"backoff_cap,omitempty"`  // upper bound for the delay
+	StableAfter Duration `json:"stable_after,omitempty"` // a run this long resets the retry counter
+}
+
+// Validate checks the mode and fills absent or zero fields with their
+// defaults.
+func (r *RestartPolicy) Validate() error {
+	switch r.Mode {
+	case "":
+		r.Mode = RestartNever
+	case RestartNever, RestartOnFailure, RestartAlways:
+	default:
+		return fmt.Errorf("invalid restart mode '%s': must be never, on-failure or always", r.Mode)
+	}
+	if (r.MaxRetries != nil && *r.MaxRetries < 0) || r.BackoffBase < 0 || r.BackoffCap < 0 || r.StableAfter < 0 {
+		return fmt.Errorf("restart policy values must not be negative")
+	}
+	// Zero is a valid limit: the first unrequested exit is fatal.
+	if r.MaxRetries == nil {
+		retries := DefaultMaxRetries
+		r.MaxRetries = &retries
+	}
+	if r.BackoffBase == 0 {
+		r.BackoffBase = Duration(DefaultBackoffBase)
+	}
+	if r.BackoffCap == 0 {
+		r.BackoffCap = Duration(DefaultBackoffCap)
+	}
+	if r.StableAfter == 0 {
+		r.StableAfter = Duration(DefaultStableAfter)
+	}
+	return nil
+}
+
+// shouldRestart reports whether an unrequested exit with the given outcome
+// calls for a restart under this policy.
+func (r *RestartPolicy) shouldRestart(crashed bool) bool {
+	if r == nil {
+		return false
+	}
+	switch r.Mode {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return crashed
+	default:
+		return false
+	}
+}
+
+// backoff returns the delay before the given restart attempt (1-based).
+func (r *RestartPolicy) backoff(attempt int) time.Duration {
+	delay := time.Duration(r.BackoffBase)
+	for i := 1; i < attempt && delay < time.Duration(r.BackoffCap); i++ {
+		delay *= 2
+	}
+	if delay > time.Duration(r.BackoffCap) {
+		delay = time.Duration(r.BackoffCap)
+	}
+	return delay
+}
+
+// scheduleRestart applies the restart policy after an unrequested exit.
+// It either arms a backoff timer or moves the process to StatFatal once the
+// retry limit is reached. The caller must hold the manager lock.
+func (p *Process) scheduleRestart(crashed bool, ranFor time.Duration) {
+	policy := p.Restart
+	if !policy.shouldRestart(crashed) {
+		return
+	}
+
+	// A run that stayed up long enough is not part of a crash loop.
+	if ranFor >= time.Duration(policy.StableAfter) {
+		p.Restarts = 0
+	}
+
+	if p.Restarts >= *policy.MaxRetries {
+		p.Stat = StatFatal
+		p.manager.logger.Error("process exceeded its restart limit, giving up", "name", p.Name, "restarts", p.Restarts)
+		return
+	}
+
+	p.Restarts++
+	delay := policy.backoff(p.Restarts)
+	p.manager.logger.Info("restarting process after backoff", "name", p.Name, "attempt", p.Restarts, "delay", delay.String())
+
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		p.manager.processMutex.Lock()
+		defer p.manager.processMutex.Unlock()
+
+		// Stop, Start or RemoveProcess may have cancelled this restart meanwhile.
+		if p.restartTimer != timer {
+			return
+		}
+		p.restartTimer = nil
+
+		if err := p.launch(p.args, TriggerRestart); err != nil {
+			p.manager.logger.Error("failed to restart process", "name", p.Name, "error", err)
+			p.Stat = StatFatal
+		}
+		if err := p.SaveState(); err != nil {
+			p.manager.logger.Error("failed to save process state after restart", "name", p.Name, "error", err)
+		}
+	})
+	p.restartTimer = timer
+}
+
+// cancelRestart stops a pending restart and reports whether there was one.
+// The caller must hold the manager lock.
+func (p *Process) cancelRestart() bool {
+	if p.restartTimer == nil {
+		return false
+	}
+	p.restartTimer.Stop()
+	p.restartTimer = nil
+	return true
+}
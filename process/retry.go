@@ -0,0 +1,187 @@
+package process
+
+import (
+	"fmt"
+	"time"
+)
+
+// Backoff strategies accepted by RetryPolicy.Backoff.
+const (
+	BackoffFixed       = "fixed"
+	BackoffExponential = "exponential"
+)
+
+// Defaults used when a RetryPolicy leaves a field at zero.
+const (
+	DefaultRetryDelay    = 10 * time.Second
+	DefaultRetryMaxDelay = 10 * time.Minute
+)
+
+// RetryPolicy controls how a failed scheduled occurrence is retried. An
+// attempt fails when it exits non-zero, is killed by a signal or times out.
+type RetryPolicy struct {
+	MaxRetries int      `json:"max_retries"`         // retries after the first attempt
+	Backoff    string   `json:"backoff,omitempty"`   // fixed (default) or exponential
+	Delay      Duration `json:"delay,omitempty"`     // delay before the first retry
+	MaxDelay   Duration `json:"max_delay,omitempty"` // upper bound for exponential backoff
+}
+
+// Validate checks the backoff strategy and fills zero fields with their
+// defaults.
+func (r *RetryPolicy) Validate() error {
+	switch r.Backoff {
+	case "":
+		r.Backoff = BackoffFixed
+	case BackoffFixed, BackoffExponential:
+	default:
+		return fmt.Errorf("invalid retry backoff '%s': must be %s or %s", r.Backoff, BackoffFixed, BackoffExponential)
+	}
+	if r.MaxRetries < 0 || r.Delay < 0 || r.MaxDelay < 0 {
+		return fmt.Errorf("retry policy values must not be negative")
+	}
+	if r.Delay == 0 {
+		r.Delay = Duration(DefaultRetryDelay)
+	}
+	if r.MaxDelay == 0 {
+		r.MaxDelay = Duration(DefaultRetryMaxDelay)
+	}
+	return nil
+}
+
+// delay returns how long to wait before the given retry, counted from 1.
+func (r *RetryPolicy) delay(retry int) time.Duration {
+	d := time.Duration(r.Delay)
+	if r.Backoff != BackoffExponential {
+		return d
+	}
+	for i := 1; i < retry && d < time.Duration(r.MaxDelay); i++ {
+		d *= 2
+	}
+	return min(d, time.Duration(r.MaxDelay))
+}
+
+// WithRetryPolicy retries failed runs of the job according to policy.
+func WithRetryPolicy(policy RetryPolicy) RuleOption {
+	return func(r *TimingRule) error {
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+		r.Retry = &policy
+		return nil
+	}
+}
+
+// pendingRetry is the next attempt of a failed occurrence.
+type pendingRetry struct {
+	occurrence string
+	attempt    int
+	timer      Timer
+	queued     bool // due, and waiting for the current run to exit
+}
+
+// scheduleRetry decides what follows a run that was an attempt of a
+// scheduled occurrence: nothing if it succeeded, another attempt after the
+// backoff, or giving up on the occurrence. It reports whether the rule's
+// retry policy handled the exit, in which case the restart policy does not
+// apply. The caller must hold the manager lock.
+func (p *Process) scheduleRetry(failed bool) bool {
+	occurrence, attempt := p.runOccurrence, p.runAttempt
+	p.runOccurrence, p.runAttempt = "", 0
+	if occurrence == "" || p.Timing == nil || p.Timing.Retry == nil {
+		return false
+	}
+	if !failed {
+		return true
+	}
+
+	policy := p.Timing.Retry
+	if attempt > policy.MaxRetries || p.IsJobDeleted == 1 {
+		p.manager.logger.Warn("scheduled occurrence failed", "name", p.Name, "occurrence", occurrence, "attempts", attempt)
+		p.recordHistory(HistoryEntry{Event: EventFailed, Reason: fmt.Sprintf("failed after %d attempt(s)", attempt), Occurrence: occurrence, Attempt: attempt})
+		return true
+	}
+
+	// Only one retry can be pending; a newer occurrence supersedes an older one.
+	p.dropRetry("superseded by a newer occurrence")
+	delay := policy.delay(attempt)
+	retry := &pendingRetry{occurrence: occurrence, attempt: attempt + 1}
+	p.retry = retry
+	p.manager.logger.Info("retrying failed scheduled run", "name", p.Name, "occurrence", occurrence, "attempt", retry.attempt, "in", delay.String())
+	p.recordHistory(HistoryEntry{Event: EventRetrying, Reason: fmt.Sprintf("attempt %d in %s", retry.attempt, delay), Occurrence: occurrence, Attempt: attempt})
+
+	retry.timer = p.manager.clock.AfterFunc(delay, func() {
+		p.manager.processMutex.Lock()
+		defer p.manager.processMutex.Unlock()
+		if p.retry != retry || retry.timer == nil {
+			return // Dropped meanwhile
+		}
+		retry.timer = nil
+		p.fireRetry(retry)
+		if err := p.SaveState(); err != nil {
+			p.manager.logger.Error("failed to save job state", "name", p.Name, "error", err)
+		}
+	})
+	return true
+}
+
+// fireRetry starts a due retry. Like any occurrence it is subject to the
+// concurrency policy if another run is going. The caller must hold the
+// manager lock.
+func (p *Process) fireRetry(retry *pendingRetry) {
+	if p.Stat == StatRunning {
+		policy := ""
+		if p.Timing != nil {
+			policy = p.Timing.Concurrency
+		}
+		switch policy {
+		case ConcurrencyAllow:
+			p.retry = nil
+			if _, err := p.startInstance(retry.occurrence, retry.attempt); err != nil {
+				p.manager.logger.Error("failed to start retry as additional instance", "name", p.Name, "error", err)
+				p.failRetry(retry, fmt.Sprintf("the retry failed to start: %v", err))
+			}
+		case ConcurrencyQueue:
+			if !retry.queued {
+				retry.queued = true
+				p.recordHistory(HistoryEntry{Event: EventQueued, Reason: "the retry waits for the current run", Pid: p.Pid, RunID: p.RunID, Occurrence: retry.occurrence, Attempt: retry.attempt})
+			}
+		default:
+			p.retry = nil
+			p.recordHistory(HistoryEntry{Event: EventSkipped, Reason: "another run is going", Pid: p.Pid, RunID: p.RunID, Occurrence: retry.occurrence, Attempt: retry.attempt})
+			p.failRetry(retry, "its retry could not run")
+		}
+		return
+	}
+
+	p.retry = nil
+	if p.IsJobDeleted == 1 {
+		p.failRetry(retry, "the job was cancelled")
+		return
+	}
+	if err := p.startLocked(p.jobStartOptions(retry.occurrence, retry.attempt)); err != nil {
+		p.manager.logger.Error("failed to start retry of scheduled run", "name", p.Name, "error", err)
+		p.failRetry(retry, fmt.Sprintf("the retry failed to start: %v", err))
+		p.jobRunExited()
+	}
+}
+
+// dropRetry abandons a pending retry. The caller must hold the manager lock.
+func (p *Process) dropRetry(reason string) {
+	retry := p.retry
+	if retry == nil {
+		return
+	}
+	p.retry = nil
+	if retry.timer != nil {
+		retry.timer.Stop()
+		retry.timer = nil
+	}
+	p.failRetry(retry, reason)
+}
+
+// failRetry records that the occurrence of retry failed for good. The
+// caller must hold the manager lock.
+func (p *Process) failRetry(retry *pendingRetry, reason string) {
+	p.manager.logger.Warn("scheduled occurrence failed", "name", p.Name, "occurrence", retry.occurrence, "reason", reason)
+	p.recordHistory(HistoryEntry{Event: EventFailed, Reason: reason, Occurrence: retry.occurrence, Attempt: retry.attempt - 1})
+}
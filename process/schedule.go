@@ -5,37 +5,342 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 )
 
-// TimingRule defines scheduling rules for a process.
+// afterCompletionSuffix marks a fixed-delay schedule such as
+// "5m after previous run finishes".
+const afterCompletionSuffix = " after previous run finishes"
+
+// minInterval is the shortest interval accepted for interval schedules.
+const minInterval = time.Second
+
+// What a cron rule does with wall-clock times that a DST change skips or
+// repeats.
+const (
+	DSTGapNext     = "next"  // run when the gap ends (default)
+	DSTGapSkip     = "skip"  // skip the occurrence
+	DSTOverlapOnce = "once"  // run on the first of the repeated times (default)
+	DSTOverlapBoth = "twice" // run on both
+)
+
+// What happens to occurrences of a job that were missed while the daemon
+// was down.
+const (
+	MisfireRunOnce = "run_once" // run once straight away (default)
+	MisfireSkip    = "skip"     // wait for the next regular occurrence
+	MisfireRunAll  = "run_all"  // run every missed occurrence, one after another
+)
+
+// TimingRule defines scheduling rules for a process. A rule fires once at
+// ScheduleTime, or recurs according to Cron, at a fixed rate (Every) or a
+// fixed delay after the previous run finished (After). Recurring rules can
+// be limited to a window. Cron expressions are read in TimeZone, or in the
+// server's local zone if it is empty.
 type TimingRule struct {
-	ScheduleTime time.Time `json:"schedule_time"`
+	ScheduleTime time.Time    `json:"schedule_time"`          // one-shot time, zero for recurring rules
+	Cron         string       `json:"cron,omitempty"`         // 5- or 6-field cron expression or @-descriptor
+	Every        Duration     `json:"every,omitempty"`        // fixed rate, measured from the window start or creation time
+	After        Duration     `json:"after,omitempty"`        // fixed delay after the previous run finished
+	WindowStart  *time.Time   `json:"window_start,omitempty"` // no occurrence before this time
+	WindowEnd    *time.Time   `json:"window_end,omitempty"`   // no occurrence after this time
+	TimeZone     string       `json:"time_zone,omitempty"`    // IANA zone such as Europe/Berlin
+	DSTGap       string       `json:"dst_gap,omitempty"`      // next or skip
+	DSTOverlap   string       `json:"dst_overlap,omitempty"`  // once or twice
+	Misfire      string       `json:"misfire,omitempty"`      // run_once, skip or run_all
+	Concurrency  string       `json:"concurrency,omitempty"`  // forbid, allow, replace or queue
+	Timeout      Duration     `json:"timeout,omitempty"`      // a run taking longer is stopped
+	Retry        *RetryPolicy `json:"retry,omitempty"`        // nil means failed runs are not retried
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+// RuleOption configures a timing rule when it is created.
+type RuleOption func(*TimingRule) error
+
+// WithWindow limits a recurring rule to occurrences between start and end.
+// A zero time leaves that side of the window open.
+func WithWindow(start, end time.Time) RuleOption {
+	return func(r *TimingRule) error {
+		if !start.IsZero() && !end.IsZero() && !end.After(start) {
+			return fmt.Errorf("window end %s must be after its start %s", end.Format(time.RFC1123), start.Format(time.RFC1123))
+		}
+		if !r.IsRecurring() {
+			return fmt.Errorf("a window only applies to recurring rules")
+		}
+		if !start.IsZero() {
+			r.WindowStart = &start
+		}
+		if !end.IsZero() {
+			r.WindowEnd = &end
+		}
+		return nil
+	}
+}
+
+// WithTimeZone reads the rule's cron expression in the IANA zone name.
+func WithTimeZone(name string) RuleOption {
+	return func(r *TimingRule) error {
+		if _, err := time.LoadLocation(name); err != nil {
+			return fmt.Errorf("invalid time zone '%s': %w", name, err)
+		}
+		r.TimeZone = name
+		return nil
+	}
+}
+
+// WithDSTPolicy sets what happens to cron occurrences that fall into a DST
+// gap (DSTGapNext or DSTGapSkip) or overlap (DSTOverlapOnce or
+// DSTOverlapBoth). Empty values keep the defaults.
+func WithDSTPolicy(gap, overlap string) RuleOption {
+	return func(r *TimingRule) error {
+		switch gap {
+		case "", DSTGapNext, DSTGapSkip:
+		default:
+			return fmt.Errorf("invalid DST gap policy '%s': must be %s or %s", gap, DSTGapNext, DSTGapSkip)
+		}
+		switch overlap {
+		case "", DSTOverlapOnce, DSTOverlapBoth:
+		default:
+			return fmt.Errorf("invalid DST overlap policy '%s': must be %s or %s", overlap, DSTOverlapOnce, DSTOverlapBoth)
+		}
+		r.DSTGap, r.DSTOverlap = gap, overlap
+		return nil
+	}
+}
+
+// WithMisfirePolicy sets what happens to occurrences missed while the
+// daemon was down: MisfireRunOnce, MisfireSkip or MisfireRunAll.
+func WithMisfirePolicy(policy string) RuleOption {
+	return func(r *TimingRule) error {
+		switch policy {
+		case "", MisfireRunOnce, MisfireSkip, MisfireRunAll:
+		default:
+			return fmt.Errorf("invalid misfire policy '%s': must be %s, %s or %s", policy, MisfireRunOnce, MisfireSkip, MisfireRunAll)
+		}
+		r.Misfire = policy
+		return nil
+	}
+}
+
+// WithConcurrencyPolicy sets what happens when an occurrence is due while
+// the previous run is still going: ConcurrencyForbid, ConcurrencyAllow,
+// ConcurrencyReplace or ConcurrencyQueue.
+func WithConcurrencyPolicy(policy string) RuleOption {
+	return func(r *TimingRule) error {
+		if err := ValidateConcurrencyPolicy(policy); err != nil {
+			return err
+		}
+		r.Concurrency = policy
+		return nil
+	}
+}
+
+// WithTimeout stops runs of the job that take longer than timeout.
+func WithTimeout(timeout time.Duration) RuleOption {
+	return func(r *TimingRule) error {
+		if timeout < 0 {
+			return fmt.Errorf("timeout must not be negative")
+		}
+		r.Timeout = Duration(timeout)
+		return nil
+	}
+}
+
+// location returns the zone the rule's cron expression is read in.
+func (r *TimingRule) location() (*time.Location, error) {
+	if r.TimeZone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(r.TimeZone)
 }
 
-// CreateTimingRule creates and saves a new timing rule.
-func (pm *ProcessManager) CreateTimingRule(ruleName string, scheduleInput string) error {
-	var scheduleTime time.Time
-	var err error
+// parseTimingRule builds a rule from user input: a Unix timestamp or an
+// RFC1123 time for a one-shot rule, "every <duration>" for a fixed rate,
+// "<duration> after previous run finishes" for a fixed delay, or a cron
+// expression.
+func parseTimingRule(input string) (TimingRule, error) {
+	input = strings.TrimSpace(input)
 
 	// Try parsing as Unix timestamp
-	if timestamp, err := strconv.ParseInt(scheduleInput, 10, 64); err == nil {
-		scheduleTime = time.Unix(timestamp, 0)
-	} else {
-		// Fallback to RFC1123 format
-		scheduleTime, err = time.Parse(time.RFC1123, scheduleInput)
+	if timestamp, err := strconv.ParseInt(input, 10, 64); err == nil {
+		return TimingRule{ScheduleTime: time.Unix(timestamp, 0)}, nil
+	}
+	// Then RFC1123 format
+	if scheduleTime, err := time.Parse(time.RFC1123, input); err == nil {
+		return TimingRule{ScheduleTime: scheduleTime}, nil
+	}
+
+	if every, ok := strings.CutPrefix(input, "every "); ok {
+		interval, err := parseInterval(every)
 		if err != nil {
-			return fmt.Errorf("invalid time format: must be Unix timestamp or RFC1123 string: %w", err)
+			return TimingRule{}, err
+		}
+		return TimingRule{Every: interval}, nil
+	}
+	if after, ok := strings.CutSuffix(input, afterCompletionSuffix); ok {
+		interval, err := parseInterval(after)
+		if err != nil {
+			return TimingRule{}, err
+		}
+		return TimingRule{After: interval}, nil
+	}
+
+	if _, err := parseCron(input); err != nil {
+		return TimingRule{}, fmt.Errorf("invalid schedule: must be a Unix timestamp, an RFC1123 time, an interval or a cron expression: %w", err)
+	}
+	return TimingRule{Cron: input}, nil
+}
+
+// parseInterval parses the duration of an interval schedule.
+func parseInterval(value string) (Duration, error) {
+	interval, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval '%s': %w", value, err)
+	}
+	if interval < minInterval {
+		return 0, fmt.Errorf("interval '%s' must be at least %s", value, minInterval)
+	}
+	return Duration(interval), nil
+}
+
+// IsRecurring reports whether the rule fires more than once.
+func (r *TimingRule) IsRecurring() bool {
+	return r.Cron != "" || r.Every > 0 || r.After > 0
+}
+
+// IsFixedDelay reports whether the rule fires relative to the end of the
+// previous run rather than on its own clock.
+func (r *TimingRule) IsFixedDelay() bool {
+	return r.After > 0
+}
+
+// Next returns the first fire time strictly after t, or false if the rule
+// has no further occurrences. For fixed-delay rules t is the time the
+// previous run finished.
+func (r *TimingRule) Next(t time.Time) (time.Time, bool) {
+	if !r.IsRecurring() {
+		if r.ScheduleTime.After(t) {
+			return r.ScheduleTime, true
 		}
+		return time.Time{}, false
 	}
 
-	rule := TimingRule{
-		ScheduleTime: scheduleTime,
+	if r.WindowStart != nil && t.Before(*r.WindowStart) {
+		// Occurrences at the very start of the window count.
+		t = r.WindowStart.Add(-time.Nanosecond)
 	}
 
-	filePath := filepath.Join(pm.config.ScheduleDir, "rules", ruleName+".json")
+	var next time.Time
+	switch {
+	case r.Cron != "":
+		schedule, err := parseCron(r.Cron)
+		if err != nil {
+			return time.Time{}, false
+		}
+		loc, err := r.location()
+		if err != nil {
+			return time.Time{}, false
+		}
+		var ok bool
+		if next, ok = schedule.next(t, loc, r.DSTGap == DSTGapSkip, r.DSTOverlap == DSTOverlapBoth); !ok {
+			return time.Time{}, false
+		}
+	case r.Every > 0:
+		anchor := r.CreatedAt
+		if r.WindowStart != nil {
+			anchor = *r.WindowStart
+		}
+		every := time.Duration(r.Every)
+		next = anchor
+		if !anchor.After(t) {
+			next = anchor.Add((t.Sub(anchor)/every + 1) * every)
+		}
+	default:
+		next = t.Add(time.Duration(r.After))
+	}
+
+	if r.WindowEnd != nil && next.After(*r.WindowEnd) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// NextFire returns the next time the rule fires after now, for listings.
+// Fixed-delay rules fire relative to their previous run and report false.
+func (r *TimingRule) NextFire(now time.Time) (time.Time, bool) {
+	if r.IsFixedDelay() {
+		return time.Time{}, false
+	}
+	return r.Next(now)
+}
+
+// first returns the first fire time of a job armed at now. A one-shot rule
+// whose time has passed and a fixed-delay rule fire straight away, or at
+// the start of their window.
+func (r *TimingRule) first(now time.Time) (time.Time, bool) {
+	switch {
+	case !r.IsRecurring() && !r.ScheduleTime.After(now):
+		return now, true
+	case r.IsFixedDelay():
+		if r.WindowEnd != nil && now.After(*r.WindowEnd) {
+			return time.Time{}, false
+		}
+		if r.WindowStart != nil && r.WindowStart.After(now) {
+			return *r.WindowStart, true
+		}
+		return now, true
+	}
+	return r.Next(now)
+}
+
+// String describes the rule for logs and the CLI.
+func (r *TimingRule) String() string {
+	var desc string
+	switch {
+	case r.Cron != "":
+		desc = "cron " + r.Cron
+		if r.TimeZone != "" {
+			desc += " (" + r.TimeZone + ")"
+		}
+	case r.Every > 0:
+		desc = "every " + time.Duration(r.Every).String()
+	case r.After > 0:
+		desc = time.Duration(r.After).String() + afterCompletionSuffix
+	default:
+		return "once at " + r.ScheduleTime.Format(time.RFC1123)
+	}
+	if r.WindowStart != nil {
+		desc += " from " + r.WindowStart.Format(time.RFC1123)
+	}
+	if r.WindowEnd != nil {
+		desc += " until " + r.WindowEnd.Format(time.RFC1123)
+	}
+	return desc
+}
+
+// CreateTimingRule creates and saves a new timing rule. scheduleInput is a
+// Unix timestamp or RFC1123 time for a one-shot rule; "every 5m" or
+// "5m after previous run finishes" for an interval; or a cron expression
+// (5 or 6 fields, or a descriptor such as @hourly).
+func (pm *ProcessManager) CreateTimingRule(ruleName string, scheduleInput string, opts ...RuleOption) error {
+	if err := ValidateName(ruleName); err != nil {
+		return err
+	}
+	rule, err := parseTimingRule(scheduleInput)
+	if err != nil {
+		return withKind(ErrInvalidSpec, err)
+	}
+	rule.CreatedAt = pm.clock.Now()
+	for _, opt := range opts {
+		if err := opt(&rule); err != nil {
+			return withKind(ErrInvalidSpec, err)
+		}
+	}
+
+	filePath := pm.rulePath(ruleName)
 	if FileExists(filePath) {
-		return fmt.Errorf("timing rule '%s' already exists", ruleName)
+		return errorf(ErrAlreadyExists, "timing rule '%s' already exists", ruleName)
 	}
 
 	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
@@ -46,62 +351,287 @@ func (pm *ProcessManager) CreateTimingRule(ruleName string, scheduleInput string
 		return fmt.Errorf("failed to save timing rule file: %w", err)
 	}
 
-	pm.logger.Info("timing rule created successfully", "name", ruleName, "time", scheduleTime.Format(time.RFC1123))
+	pm.logger.Info("timing rule created successfully", "name", ruleName, "rule", rule.String())
 	return nil
 }
 
-// SetJob assigns a timing rule to a process.
+// rulePath returns the file a timing rule is saved in. The name must have
+// passed ValidateName.
+func (pm *ProcessManager) rulePath(ruleName string) string {
+	return filepath.Join(pm.config.ScheduleDir, "rules", ruleName+".json")
+}
+
+// TimingRule loads a saved timing rule by its name.
+func (pm *ProcessManager) TimingRule(ruleName string) (*TimingRule, error) {
+	if err := ValidateName(ruleName); err != nil {
+		return nil, err
+	}
+	filePath := pm.rulePath(ruleName)
+	if !FileExists(filePath) {
+		return nil, errorf(ErrNotFound, "timing rule '%s' not found", ruleName)
+	}
+	rule := &TimingRule{}
+	if err := LoadFromFile(filePath, rule); err != nil {
+		return nil, fmt.Errorf("failed to load timing rule '%s': %w", ruleName, err)
+	}
+	return rule, nil
+}
+
+// TimingRules loads all saved timing rules, keyed by name.
+func (pm *ProcessManager) TimingRules() (map[string]*TimingRule, error) {
+	files, err := filepath.Glob(pm.rulePath("*"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list timing rules: %w", err)
+	}
+	rules := make(map[string]*TimingRule, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		rule, err := pm.TimingRule(name)
+		if err != nil {
+			return nil, err
+		}
+		rules[name] = rule
+	}
+	return rules, nil
+}
+
+// DeleteTimingRule deletes a saved timing rule. A rule that is set as the
+// job of a process cannot be deleted until the job is unset.
+func (pm *ProcessManager) DeleteTimingRule(ruleName string) error {
+	if err := ValidateName(ruleName); err != nil {
+		return err
+	}
+	filePath := pm.rulePath(ruleName)
+	if !FileExists(filePath) {
+		return errorf(ErrNotFound, "timing rule '%s' not found", ruleName)
+	}
+
+	pm.processMutex.Lock()
+	defer pm.processMutex.Unlock()
+	for _, p := range pm.Processes {
+		if p.JobRule == ruleName {
+			return errorf(ErrInUse, "timing rule '%s' is the job of process '%s', unset it first", ruleName, p.Name)
+		}
+	}
+
+	if err := os.Remove(filePath); err != nil {
+		return fmt.Errorf("failed to delete timing rule file: %w", err)
+	}
+	pm.logger.Info("timing rule deleted", "name", ruleName)
+	return nil
+}
+
+// SetJob assigns a timing rule to a process. If the job is already armed,
+// its pending occurrence is re-armed on the new rule.
 func (p *Process) SetJob(timingRuleName string) error {
+	p.manager.processMutex.Lock()
+	defer p.manager.processMutex.Unlock()
+
 	if p.Schedul != 1 {
-		return fmt.Errorf("process '%s' is not configured for automatic scheduling", p.Name)
+		return errorf(ErrNotScheduled, "process '%s' is not configured for automatic scheduling", p.Name)
 	}
 
-	rule := TimingRule{}
-	filePath := filepath.Join(p.manager.config.ScheduleDir, "rules", timingRuleName+".json")
-	if err := LoadFromFile(filePath, &rule); err != nil {
-		return fmt.Errorf("failed to load timing rule '%s': %w", timingRuleName, err)
+	rule, err := p.manager.TimingRule(timingRuleName)
+	if err != nil {
+		return err
 	}
 
-	p.Timing = &rule
-	p.manager.logger.Info("job set for process", "name", p.Name, "time", p.Timing.ScheduleTime.Format(time.RFC1123))
+	p.Timing = rule
+	p.JobRule = timingRuleName
+	p.manager.logger.Info("job set for process", "name", p.Name, "rule", rule.String())
+
+	if p.jobTimer != nil {
+		p.jobTimer.Stop()
+		p.jobTimer = nil
+		p.NextRun = time.Time{}
+		if next, ok := rule.first(p.manager.clock.Now()); ok {
+			p.armJob(next)
+		}
+		p.JobActive = p.jobTimer != nil
+	}
 
 	// Save the process state with the new timing information
 	return p.SaveState()
 }
 
-// StartJob starts a process based on its schedule.
+// StartJob arms the process's schedule. A one-shot rule whose time has
+// already passed fires immediately. Recurring rules compute their next fire
+// time after every occurrence and keep running until the job is cancelled.
 func (p *Process) StartJob() error {
-	if p.Stat == 1 {
-		p.manager.logger.Warn("cannot start job, process is already running", "name", p.Name)
-		return nil // Not an error, just can't start again
+	p.manager.processMutex.Lock()
+	defer p.manager.processMutex.Unlock()
+
+	if p.Timing == nil {
+		return errorf(ErrNotScheduled, "process '%s' has no job timing configured", p.Name)
+	}
+	if p.jobTimer != nil {
+		return errorf(ErrAlreadyRunning, "job for process '%s' is already scheduled for %s", p.Name, p.NextRun.Format(time.RFC1123))
+	}
+	if p.jobAwaitingExit {
+		return errorf(ErrAlreadyRunning, "job for process '%s' is already active and waits for the current run to finish", p.Name)
 	}
 
+	next, ok := p.Timing.first(p.manager.clock.Now())
+	if !ok {
+		return errorf(ErrNotScheduled, "timing rule of process '%s' has no future occurrences", p.Name)
+	}
+
+	p.IsJobDeleted = 0
+	p.JobActive = true
+	p.armJob(next)
+	return p.SaveState()
+}
+
+// CancelJob marks the job of the process as deleted and disarms its pending
+// occurrence. A run that is in progress is not stopped. The job can be
+// started again with StartJob.
+func (p *Process) CancelJob() error {
+	p.manager.processMutex.Lock()
+	defer p.manager.processMutex.Unlock()
+
 	if p.Timing == nil {
-		return fmt.Errorf("process '%s' has no job timing configured", p.Name)
+		return errorf(ErrNotScheduled, "process '%s' has no job timing configured", p.Name)
 	}
+	retrying := p.retry != nil
+	if !p.cancelJob() && !retrying {
+		return errorf(ErrNotRunning, "job for process '%s' is not active", p.Name)
+	}
+	p.IsJobDeleted = 1
+	p.manager.logger.Info("job cancelled", "name", p.Name)
+	return p.SaveState()
+}
 
-	now := time.Now()
-	scheduleTime := p.Timing.ScheduleTime
+// UnsetJob cancels the job of the process, if any, and removes its timing
+// rule.
+func (p *Process) UnsetJob() error {
+	p.manager.processMutex.Lock()
+	defer p.manager.processMutex.Unlock()
 
-	if now.Before(scheduleTime) {
-		waitDuration := time.Until(scheduleTime)
-		p.manager.logger.Info("job scheduled for the future, waiting...", "name", p.Name, "duration", waitDuration.String())
+	if p.Timing == nil {
+		return errorf(ErrNotScheduled, "process '%s' has no job timing configured", p.Name)
+	}
+	p.cancelJob()
+	p.Timing = nil
+	p.JobRule = ""
+	p.IsJobDeleted = 0
+	p.manager.logger.Info("job unset for process", "name", p.Name)
+	return p.SaveState()
+}
 
-		// Non-blocking wait
-		time.AfterFunc(waitDuration, func() {
-			if p.IsJobDeleted == 1 {
-				p.manager.logger.Info("job was deleted before it could run", "name", p.Name)
-				return
-			}
-			p.manager.logger.Info("scheduled time reached, starting process", "name", p.Name)
-			if err := p.Start(); err != nil {
-				p.manager.logger.Error("failed to auto-start scheduled process", "name", p.Name, "error", err)
+// armJob schedules the next occurrence of the job. The caller must hold the
+// manager lock.
+func (p *Process) armJob(at time.Time) {
+	clock := p.manager.clock
+	delay := at.Sub(clock.Now())
+	p.NextRun = at
+	p.manager.logger.Info("job scheduled", "name", p.Name, "at", at.Format(time.RFC1123), "in", delay.Round(time.Second).String())
+
+	var timer Timer
+	timer = clock.AfterFunc(delay, func() {
+		p.manager.processMutex.Lock()
+		defer p.manager.processMutex.Unlock()
+
+		if p.jobTimer != timer {
+			return // Cancelled or re-armed meanwhile
+		}
+		p.jobTimer = nil
+		p.NextRun = time.Time{}
+		p.fireJob()
+
+		if p.IsJobDeleted == 0 && p.Timing != nil {
+			if p.Timing.IsFixedDelay() && p.Stat == StatRunning {
+				// The next occurrence is armed when this run exits.
+				p.jobAwaitingExit = true
+			} else if next, ok := p.Timing.Next(clock.Now()); ok {
+				p.armJob(next)
 			}
-		})
-		return nil
+		}
+		p.JobActive = p.jobTimer != nil || p.jobAwaitingExit
+		if err := p.SaveState(); err != nil {
+			p.manager.logger.Error("failed to save job state", "name", p.Name, "error", err)
+		}
+	})
+	p.jobTimer = timer
+}
+
+// fireJob runs one occurrence of the job. The caller must hold the manager lock.
+func (p *Process) fireJob() {
+	if p.IsJobDeleted == 1 {
+		p.manager.logger.Info("job was deleted before it could run", "name", p.Name)
+		return
+	}
+	if (p.Stat == StatRunning || p.retry != nil) && !p.overlap() {
+		return
 	}
 
-	// If the time has already passed, start it immediately
-	p.manager.logger.Info("job schedule is in the past, starting immediately", "name", p.Name)
-	return p.Start()
-}
\ No newline at end of file
+	p.manager.logger.Info("scheduled time reached, starting process", "name", p.Name)
+	if err := p.startOccurrence(); err != nil {
+		p.manager.logger.Error("failed to auto-start scheduled process", "name", p.Name, "error", err)
+	}
+}
+
+// startOccurrence starts the first attempt of a new occurrence of the job.
+// The caller must hold the manager lock.
+func (p *Process) startOccurrence() error {
+	return p.startLocked(p.jobStartOptions(newRunID(p.manager.clock.Now()), 1))
+}
+
+// jobStartOptions returns how an attempt of an occurrence of the job is
+// started.
+func (p *Process) jobStartOptions(occurrence string, attempt int) StartOptions {
+	opts := StartOptions{Trigger: TriggerSchedule, occurrence: occurrence, attempt: attempt}
+	if p.Timing != nil {
+		opts.Timeout = time.Duration(p.Timing.Timeout)
+	}
+	return opts
+}
+
+// cancelJob disarms a pending job occurrence. It reports whether one was
+// pending. The caller must hold the manager lock.
+func (p *Process) cancelJob() bool {
+	p.JobActive = false
+	p.jobBacklog = 0
+	p.dropRetry("the job was cancelled")
+	if p.jobAwaitingExit {
+		p.jobAwaitingExit = false
+		return true
+	}
+	if p.jobTimer == nil {
+		return false
+	}
+	p.jobTimer.Stop()
+	p.jobTimer = nil
+	p.NextRun = time.Time{}
+	return true
+}
+
+// jobRunExited continues a job once its run has finished for good: it
+// starts a queued retry or the next occurrence still owed, or arms the next
+// occurrence of a fixed-delay job. The caller must hold the manager lock.
+func (p *Process) jobRunExited() {
+	if p.restartTimer != nil {
+		return // The run continues after a restart
+	}
+	if p.retry != nil {
+		if p.retry.queued {
+			p.fireRetry(p.retry)
+		}
+		return // The occurrence is not done until its retry is
+	}
+	for p.jobBacklog > 0 && p.IsJobDeleted == 0 && p.Stat != StatRunning {
+		p.jobBacklog--
+		p.manager.logger.Info("running missed job occurrence", "name", p.Name, "remaining", p.jobBacklog)
+		p.fireJob()
+	}
+
+	if !p.jobAwaitingExit || p.Stat == StatRunning {
+		return
+	}
+	p.jobAwaitingExit = false
+	if p.IsJobDeleted == 0 && p.Timing != nil {
+		if next, ok := p.Timing.Next(p.EndTime); ok {
+			p.armJob(next)
+		}
+	}
+	p.JobActive = p.jobTimer != nil
+}
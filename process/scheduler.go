@@ -0,0 +1,136 @@
+package process
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// maxMissedRuns caps how many missed occurrences MisfireRunAll catches up
+// on, so a daemon that was down for a long time does not start a flood.
+const maxMissedRuns = 100
+
+// Scheduler re-arms the jobs of persisted processes when the daemon starts
+// and disarms them when it shuts down. Occurrences missed while the daemon
+// was down are handled according to each rule's misfire policy.
+type Scheduler struct {
+	pm *ProcessManager
+}
+
+// NewScheduler creates a scheduler for the jobs of pm.
+func NewScheduler(pm *ProcessManager) *Scheduler {
+	return &Scheduler{pm: pm}
+}
+
+// Start re-arms every job that was active when the daemon last stopped. It
+// is meant to run once, after LoadProcessesFromDisk.
+func (s *Scheduler) Start() {
+	pm := s.pm
+	pm.processMutex.Lock()
+	defer pm.processMutex.Unlock()
+
+	now := pm.clock.Now()
+	resumed := 0
+	for _, p := range pm.Processes {
+		if p.Timing == nil || !p.JobActive || p.IsJobDeleted == 1 || p.jobTimer != nil {
+			continue
+		}
+		s.resume(p, now)
+		if err := p.SaveState(); err != nil {
+			pm.logger.Error("failed to save job state", "name", p.Name, "error", err)
+		}
+		resumed++
+	}
+	if resumed > 0 {
+		pm.logger.Info("scheduler resumed jobs", "count", resumed)
+	}
+}
+
+// Stop disarms all pending jobs. They stay active on disk, so the next
+// Start picks them up again.
+func (s *Scheduler) Stop() {
+	s.pm.processMutex.Lock()
+	defer s.pm.processMutex.Unlock()
+
+	for _, p := range s.pm.Processes {
+		if p.jobTimer != nil {
+			p.jobTimer.Stop()
+			p.jobTimer = nil
+		}
+	}
+}
+
+// ScheduledJob is the next armed occurrence of a job.
+type ScheduledJob struct {
+	Process  string    `json:"process"`
+	Rule     string    `json:"rule,omitempty"`
+	Schedule string    `json:"schedule"`
+	NextRun  time.Time `json:"next_run"`
+}
+
+// Upcoming returns the next armed occurrence of every job, soonest first.
+// Fixed-delay jobs waiting for their run to finish have none yet.
+func (pm *ProcessManager) Upcoming() []ScheduledJob {
+	pm.processMutex.Lock()
+	defer pm.processMutex.Unlock()
+
+	var jobs []ScheduledJob
+	for _, p := range pm.Processes {
+		if p.jobTimer == nil || p.Timing == nil {
+			continue
+		}
+		jobs = append(jobs, ScheduledJob{Process: p.Name, Rule: p.JobRule, Schedule: p.Timing.String(), NextRun: p.NextRun})
+	}
+	slices.SortFunc(jobs, func(a, b ScheduledJob) int { return a.NextRun.Compare(b.NextRun) })
+	return jobs
+}
+
+// resume re-arms the job of p, applying its misfire policy if the
+// persisted fire time has passed. The caller must hold the manager lock.
+func (s *Scheduler) resume(p *Process, now time.Time) {
+	logger := s.pm.logger
+	rule := p.Timing
+
+	due := p.NextRun
+	if due.IsZero() {
+		// The daemon went down while a fixed-delay run was in progress. How
+		// long ago that run ended is unknown, so the delay counts from now.
+		if next, ok := rule.Next(now); ok {
+			p.armJob(next)
+		}
+		p.JobActive = p.jobTimer != nil
+		return
+	}
+	if due.After(now) {
+		p.armJob(due)
+		return
+	}
+
+	missed := 1
+	if !rule.IsFixedDelay() {
+		for at := due; missed < maxMissedRuns; missed++ {
+			next, ok := rule.Next(at)
+			if !ok || next.After(now) {
+				break
+			}
+			at = next
+		}
+	}
+
+	switch rule.Misfire {
+	case MisfireSkip:
+		logger.Warn("skipping job occurrences missed while the daemon was down", "name", p.Name, "missed", missed)
+		p.recordHistory(HistoryEntry{Event: EventSkipped, Reason: fmt.Sprintf("%d occurrence(s) missed while the daemon was down", missed)})
+		if next, ok := rule.Next(now); ok {
+			p.armJob(next)
+		}
+	case MisfireRunAll:
+		logger.Warn("running job occurrences missed while the daemon was down", "name", p.Name, "missed", missed)
+		p.jobBacklog = missed - 1
+		p.armJob(now)
+	default:
+		logger.Warn("running job once for occurrences missed while the daemon was down", "name", p.Name, "missed", missed)
+		p.armJob(now)
+	}
+	p.JobActive = p.jobTimer != nil
+}
@@ -0,0 +1,118 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Defaults used by Stop when a process does not configure its own values.
+const (
+	DefaultStopSignal  = "SIGTERM"
+	DefaultStopTimeout = 10 * time.Second
+)
+
+// signals lists the signals that can be configured by name.
+var signals = map[string]syscall.Signal{
+	"SIGHUP":   syscall.SIGHUP,
+	"SIGINT":   syscall.SIGINT,
+	"SIGQUIT":  syscall.SIGQUIT,
+	"SIGKILL":  syscall.SIGKILL,
+	"SIGUSR1":  syscall.SIGUSR1,
+	"SIGUSR2":  syscall.SIGUSR2,
+	"SIGTERM":  syscall.SIGTERM,
+	"SIGCONT":  syscall.SIGCONT,
+	"SIGSTOP":  syscall.SIGSTOP,
+	"SIGWINCH": syscall.SIGWINCH,
+}
+
+// maxSignal is the highest standard signal number; real-time signals and
+// anything above are not accepted.
+const maxSignal = 31
+
+// ParseSignal resolves a signal given as a name ("SIGTERM", "term") or a
+// number ("15") and returns it together with its canonical name.
+func ParseSignal(value string) (syscall.Signal, string, error) {
+	if num, err := strconv.Atoi(value); err == nil {
+		// Zero and negative numbers would not send a signal at all, or mean
+		// something else to kill(2), so only real signal numbers pass.
+		if num < 1 || num > maxSignal {
+			return 0, "", errorf(ErrInvalidSpec, "invalid signal number %d: must be between 1 and %d", num, maxSignal)
+		}
+		sig := syscall.Signal(num)
+		return sig, signalName(sig), nil
+	}
+
+	name := strings.ToUpper(strings.TrimSpace(value))
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	sig, ok := signals[name]
+	if !ok {
+		return 0, "", errorf(ErrInvalidSpec, "unknown signal '%s'", value)
+	}
+	return sig, name, nil
+}
+
+// nonTerminating lists the signals whose default action is to be ignored,
+// to stop or to continue the process. With them as the stop signal every
+// stop would wait out its timeout and end with SIGKILL.
+var nonTerminating = []syscall.Signal{
+	syscall.SIGCHLD, syscall.SIGURG, syscall.SIGWINCH, // ignored
+	syscall.SIGSTOP, syscall.SIGTSTP, syscall.SIGTTIN, syscall.SIGTTOU, // stop
+	syscall.SIGCONT, // continue
+}
+
+// parseStopSignal is ParseSignal for stop signals, which must end the
+// process unless it handles them.
+func parseStopSignal(value string) (syscall.Signal, string, error) {
+	sig, name, err := ParseSignal(value)
+	if err != nil {
+		return 0, "", err
+	}
+	if slices.Contains(nonTerminating, sig) {
+		return 0, "", errorf(ErrInvalidSpec, "%s cannot be used as a stop signal, it does not end the process by default", name)
+	}
+	return sig, name, nil
+}
+
+// signalName returns the canonical name of a signal, such as "SIGTERM".
+func signalName(sig syscall.Signal) string {
+	for name, s := range signals {
+		if s == sig {
+			return name
+		}
+	}
+	return sig.String()
+}
+
+// Signal sends a signal, given as for ParseSignal, to the process group of
+// the running process and of any extra instances. Unlike Stop it does not
+// wait for anything.
+func (p *Process) Signal(value string) error {
+	sig, name, err := ParseSignal(value)
+	if err != nil {
+		return err
+	}
+
+	p.manager.processMutex.Lock()
+	defer p.manager.processMutex.Unlock()
+
+	if p.Stat != StatRunning && len(p.instances) == 0 {
+		return errorf(ErrNotRunning, "process '%s' is not running", p.Name)
+	}
+	if err := p.signal(sig); err != nil {
+		return fmt.Errorf("failed to send %s to process: %w", name, err)
+	}
+	for pid := range p.instances {
+		if err := syscall.Kill(-pid, sig); err != nil && !errors.Is(err, syscall.ESRCH) {
+			p.manager.logger.Error("failed to signal process instance", "name", p.Name, "pid", pid, "signal", name, "error", err)
+		}
+	}
+	p.manager.logger.Info("signal sent to process", "name", p.Name, "signal", name)
+	return nil
+}
@@ -0,0 +1,107 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// ExitReasonTimedOut is recorded as the exit reason of a run that was
+// stopped because it exceeded its run timeout.
+const ExitReasonTimedOut = "timed_out"
+
+// EventTimedOut is the job history event of a run stopped at its deadline.
+const EventTimedOut = "timed_out"
+
+// armTimeout stops the run started as cmd once the run timeout has passed.
+// Only that run is stopped; extra instances of the process keep running.
+// The caller must hold the manager lock.
+func (p *Process) armTimeout(cmd *exec.Cmd) {
+	timeout := p.runTimeout
+	p.timeoutTimer = p.manager.clock.AfterFunc(timeout, func() {
+		p.manager.processMutex.Lock()
+		if p.process != cmd || p.Stat != StatRunning {
+			p.manager.processMutex.Unlock()
+			return // The run has already ended
+		}
+		p.timeoutTimer = nil
+		p.timedOut = true
+		p.stopping = true // Not a crash, and not to be restarted
+		pid, runID, done := p.Pid, p.RunID, p.done
+		sig, grace := p.stopSettings()
+		p.manager.processMutex.Unlock()
+
+		p.manager.logger.Warn("run exceeded its timeout, stopping it", "name", p.Name, "pid", pid, "timeout", timeout.String())
+		p.recordHistory(HistoryEntry{Event: EventTimedOut, Reason: fmt.Sprintf("exceeded the timeout of %s", timeout), Pid: pid, RunID: runID})
+		p.stopGroup(pid, done, sig, grace)
+	})
+}
+
+// cancelTimeout disarms the run timeout. The caller must hold the manager lock.
+func (p *Process) cancelTimeout() {
+	if p.timeoutTimer != nil {
+		p.timeoutTimer.Stop()
+		p.timeoutTimer = nil
+	}
+}
+
+// armInstanceTimeout stops an extra instance once timeout has passed. The
+// caller must hold the manager lock.
+func (p *Process) armInstanceTimeout(pid int, inst *instance, timeout time.Duration) {
+	inst.timer = p.manager.clock.AfterFunc(timeout, func() {
+		p.manager.processMutex.Lock()
+		if p.instances[pid] != inst {
+			p.manager.processMutex.Unlock()
+			return // The instance has already ended
+		}
+		inst.timer = nil
+		inst.timedOut = true
+		sig, grace := p.stopSettings()
+		p.manager.processMutex.Unlock()
+
+		p.manager.logger.Warn("job instance exceeded its timeout, stopping it", "name", p.Name, "pid", pid, "timeout", timeout.String())
+		p.recordHistory(HistoryEntry{Event: EventTimedOut, Reason: fmt.Sprintf("exceeded the timeout of %s", timeout), Pid: pid, RunID: inst.runID})
+		p.stopGroup(pid, inst.done, sig, grace)
+	})
+}
+
+// stopSettings returns the configured stop signal and timeout, or their
+// defaults. The caller must hold the manager lock.
+func (p *Process) stopSettings() (syscall.Signal, time.Duration) {
+	sig, _, err := parseStopSignal(p.StopSignal)
+	if p.StopSignal == "" || err != nil {
+		sig = syscall.SIGTERM
+	}
+	grace := time.Duration(p.StopTimeout)
+	if grace <= 0 {
+		grace = DefaultStopTimeout
+	}
+	return sig, grace
+}
+
+// stopGroup stops a single run, which leads the process group pgid and whose
+// watcher closes done: the group gets sig, and SIGKILL if anything in it is
+// left once grace has passed.
+func (p *Process) stopGroup(pgid int, done <-chan struct{}, sig syscall.Signal, grace time.Duration) {
+	if err := syscall.Kill(-pgid, sig); err != nil && !errors.Is(err, syscall.ESRCH) {
+		p.manager.logger.Error("failed to signal process group", "name", p.Name, "pgid", pgid, "error", err)
+	}
+	deadline := time.After(grace)
+	select {
+	case <-done:
+	case <-deadline:
+		p.killGroup(pgid)
+		return
+	}
+	// Workers may outlive the group leader until the grace period is over.
+	for groupAlive(pgid) {
+		select {
+		case <-deadline:
+			p.killGroup(pgid)
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
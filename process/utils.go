@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 )
 
 // SaveToFile marshals data to JSON and saves it to a file.
@@ -34,8 +36,47 @@ func LoadFromFile(filePath string, target interface{}) error {
 	return nil
 }
 
+// ValidateName checks the name of a process or timing rule. Names become
+// part of file paths, so they must not be able to leave their directory.
+func ValidateName(name string) error {
+	if name == "" || name == "." || strings.Contains(name, "..") || strings.ContainsAny(name, "/\\\x00") {
+		return errorf(ErrInvalidSpec, "invalid name '%s': must not be empty or contain '/', '\\' or '..'", name)
+	}
+	return nil
+}
+
 // FileExists checks if a file or directory exists at the given path.
 func FileExists(path string) bool {
 	_, err := os.Stat(path)
 	return !os.IsNotExist(err)
-}
\ No newline at end of file
+}
+
+// Duration is a time.Duration that is stored in JSON as a string such as
+// "1m30s". Plain numbers are accepted on input and read as seconds.
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case float64:
+		*d = Duration(v * float64(time.Second))
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", v, err)
+		}
+		*d = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration %s", string(data))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package process
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// watch waits for cmd to exit, records how it ended and persists the result.
+// It runs in its own goroutine for every started process and is the only
+// caller of cmd.Wait, which also reaps the child so no zombie is left behind.
+func (p *Process) watch(cmd *exec.Cmd, done chan struct{}) {
+	defer close(done)
+
+	_ = cmd.Wait() // The outcome is read from cmd.ProcessState below
+
+	pm := p.manager
+	pm.processMutex.Lock()
+	defer pm.processMutex.Unlock()
+
+	p.EndTime = time.Now()
+	p.ExitCode, p.ExitSignal = exitDetails(cmd.ProcessState)
+	p.cancelTimeout()
+	switch {
+	case p.timedOut:
+		p.ExitReason = ExitReasonTimedOut
+	case p.oomKilled():
+		p.ExitReason = ExitReasonOOMKilled
+	case p.stopping:
+		p.ExitReason = ExitReasonStopped
+	}
+
+	p.recordRunEnd(p.RunID, p.Pid, p.EndTime, p.ExitCode, p.ExitSignal, p.ExitReason)
+
+	crashed := !p.stopping && !cmd.ProcessState.Success()
+	if crashed {
+		p.Stat = StatCrashed
+		pm.logger.Warn("process crashed", "name", p.Name, "pid", p.Pid, "exit_code", p.ExitCode, "signal", p.ExitSignal, "reason", p.ExitReason)
+	} else {
+		p.Stat = StatStopped
+		pm.logger.Info("process exited", "name", p.Name, "pid", p.Pid, "exit_code", p.ExitCode, "signal", p.ExitSignal)
+	}
+
+	p.Pid = 0
+	p.process = nil
+
+	// Failed scheduled runs are retried by their job if it has a retry
+	// policy. Otherwise only exits the manager did not ask for are subject
+	// to the restart policy.
+	retried := p.scheduleRetry(crashed || p.timedOut)
+	if !p.stopping && !retried {
+		p.scheduleRestart(crashed, p.EndTime.Sub(p.StartTime))
+	}
+	p.stopping = false
+	p.jobRunExited()
+
+	if err := p.SaveState(); err != nil {
+		pm.logger.Error("failed to save process state after exit", "name", p.Name, "error", err)
+	}
+}
+
+// exitDetails extracts the exit code and, if the child was killed by a
+// signal, the signal name from a finished process.
+func exitDetails(state *os.ProcessState) (int, string) {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return -1, signalName(status.Signal())
+	}
+	return state.ExitCode(), ""
+}